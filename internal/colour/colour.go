@@ -29,11 +29,51 @@ var (
 	ResultsTableBorder      = lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#494d64"}
 	StatusBarBG             = darkGrey
 	StatusBarFG             = blue
+	StatusBarMessageFG      = yellow
 	TitleBarBG              = darkGrey
 	TitleBarFG              = blue
 	Error                   = red
+	Warning                 = orange
+	Info                    = blue
 	ResultRowPopupTitleBG   = yellow
 	HelpBorder              = orange
 	HelpKey                 = orange
 	HelpDesc                = lipgloss.NoColor{}
+	FormPopupTitleBG        = teal
+	FormLabel               = lightGrey
+	FormLabelActive         = yellow
+	ConfirmPopupTitleBG     = orange
+	ExplainPopupTitleBG     = blue
+	ExplainHotNode          = red
+	ExplainSelectedBG       = yellow
+	ReadOnlyBadgeBG         = red
+	ReadOnlyBadgeFG         = black
+	TransactionBadgeBG      = orange
+	TransactionBadgeFG      = black
+	CellViewerPopupTitleBG  = teal
+	CellViewerMatchBG       = orange
+	ColumnStatsPopupTitleBG = blue
+	ChartPopupTitleBG       = green
+	ChartAxis               = lightGrey
+	ChartSeries             = []lipgloss.TerminalColor{blue, orange, green, yellow, red, teal}
+	DiffPopupTitleBG        = orange
+	DiffAdded               = green
+	DiffRemoved             = red
+	DiffChanged             = yellow
+	DiffChangedCellBG       = yellow
+	DiffChangedCellFG       = black
+	MessageLogPopupTitleBG  = red
+	MessageLogSelectedBG    = yellow
+	ErrorLineBG             = darkGrey
+	ErrorTokenBG            = red
+	ErrorTokenFG            = black
+	CommandPaletteTitleBG   = green
+	CommandPaletteCursorBG  = yellow
+	CommandPaletteMatchFG   = orange
+	VimSelectionBG          = blue
+	VimSelectionFG          = black
+	FindMatchBG             = yellow
+	FindCurrentMatchBG      = orange
+	FindMatchFG             = black
+	FindOptionOnBG          = teal
 )
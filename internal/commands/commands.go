@@ -1,17 +1,41 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
 	"github.com/wheelibin/qrypad/internal/db"
+	"github.com/wheelibin/qrypad/internal/keys"
 )
 
+// the name of the query buffer created when a database has none
+const DefaultQueryBufferName = "default"
+
+// the file the panel layout is saved to, next to the config file
+const layoutFileName = "layout.json"
+
+// the panel sizes, saved so they are kept from one session to the next
+type Layout struct {
+	// the width of the tables panels, as a percentage of the window width
+	LeftWidth int `json:"leftWidth"`
+	// the height of the results panel, as a percentage of the height of the query and results panels
+	ResultsHeight int  `json:"resultsHeight"`
+	LeftHidden    bool `json:"leftHidden"`
+}
+
 type TableInfoKindType string
 
 var TableInfoKind = struct {
@@ -22,9 +46,9 @@ var TableInfoKind = struct {
 	Indexes: "inds",
 }
 
-func GetTableRows(dbConn db.DBConn, tableName string) tea.Cmd {
+func GetTableRows(dbConn db.DBConn, q db.TableQuery) tea.Cmd {
 	return tea.Batch(func() tea.Msg {
-		data, err := db.GetTableRows(dbConn, tableName)
+		data, err := db.GetTableRows(dbConn, q)
 		if err != nil {
 			return ErrMsg{err}
 		}
@@ -40,9 +64,9 @@ func GetTableInfo(dbConn db.DBConn, tableName string, kind TableInfoKindType) te
 		)
 		switch kind {
 		case TableInfoKind.Columns:
-			data, err = db.GetTableColumns(dbConn, tableName)
+			data, err = db.GetTableColumns(dbConn, "", tableName)
 		case TableInfoKind.Indexes:
-			data, err = db.GetTableIndexes(dbConn, tableName)
+			data, err = db.GetTableIndexes(dbConn, "", tableName)
 		}
 		if err != nil {
 			return ErrMsg{err}
@@ -61,16 +85,92 @@ func GetSchemaTables(dbConn db.DBConn) tea.Cmd {
 	}
 }
 
-func ExecuteQuery(dbConn db.DBConn, query string) tea.Cmd {
+func ExecuteQuery(dbConn db.DBConn, query string, args ...any) tea.Cmd {
 	return tea.Batch(func() tea.Msg {
-		data, err := db.ExecuteQuery(dbConn, query)
+		data, err := db.ExecuteQuery(dbConn, query, args...)
 		if err != nil {
-			return ErrMsg{err}
+			return StatementErrMsg{ErrMsg: ErrMsg{err}, Query: query}
 		}
 		return db.DataMsg(data)
 	}, SetLoading(true))
 }
 
+func Explain(dbConn db.DBConn, query string, analyze bool, args ...any) tea.Cmd {
+	return tea.Batch(func() tea.Msg {
+		plan, err := db.Explain(dbConn, query, analyze, args...)
+		if err != nil {
+			return ErrMsg{err}
+		}
+		return db.ExplainMsg(plan)
+	}, SetLoading(true))
+}
+
+func GetEditableTable(dbConn db.DBConn, table string) tea.Cmd {
+	return func() tea.Msg {
+		editable, err := db.GetEditableTable(dbConn, table)
+		if err != nil {
+			return ErrMsg{err}
+		}
+		return db.EditableTableMsg(editable)
+	}
+}
+
+func GetColumnStats(dbConn db.DBConn, table string, column string) tea.Cmd {
+	return tea.Batch(func() tea.Msg {
+		stats, err := db.GetColumnStats(dbConn, table, column)
+		if err != nil {
+			return ErrMsg{err}
+		}
+		return db.ColumnStatsMsg(stats)
+	}, SetLoading(true))
+}
+
+func FetchForCompare(connect db.ConnectFunc, alias string, driverName string, query string, args ...any) tea.Cmd {
+	return tea.Batch(func() tea.Msg {
+		data, err := db.FetchForCompare(connect, alias, driverName, query, args...)
+		if err != nil {
+			return ErrMsg{err}
+		}
+		return db.CompareDataMsg(&db.CompareData{Description: alias, Data: data})
+	}, SetLoading(true))
+}
+
+func ExecuteRowStatement(dbConn db.DBConn, query string, args ...any) tea.Cmd {
+	return tea.Batch(func() tea.Msg {
+		if err := db.ExecuteRowStatement(dbConn, query, args...); err != nil {
+			return ErrMsg{err}
+		}
+		return RowStatementExecutedMsg{}
+	}, SetLoading(true))
+}
+
+func BeginTransaction(dbConn db.DBConn) tea.Cmd {
+	return func() tea.Msg {
+		if err := dbConn.Begin(); err != nil {
+			return ErrMsg{err}
+		}
+		return TransactionChangedMsg{Notice: "transaction started"}
+	}
+}
+
+func CommitTransaction(dbConn db.DBConn) tea.Cmd {
+	return func() tea.Msg {
+		if err := dbConn.Commit(); err != nil {
+			return ErrMsg{err}
+		}
+		return TransactionChangedMsg{Notice: "transaction committed"}
+	}
+}
+
+func RollbackTransaction(dbConn db.DBConn) tea.Cmd {
+	return func() tea.Msg {
+		if err := dbConn.Rollback(); err != nil {
+			return ErrMsg{err}
+		}
+		return TransactionChangedMsg{Notice: "transaction rolled back"}
+	}
+}
+
 func SetActivePanel(panelIndex int) tea.Cmd {
 	return func() tea.Msg {
 		return ActivePanelChangedMsg(panelIndex)
@@ -89,28 +189,88 @@ func SetLoading(loading bool) tea.Cmd {
 	}
 }
 
+func SubmitForm(id string, values map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		return FormSubmittedMsg{ID: id, Values: values}
+	}
+}
+
+func CancelForm(id string) tea.Cmd {
+	return func() tea.Msg {
+		return FormCancelledMsg{ID: id}
+	}
+}
+
+func ChooseCommand(command keys.Command) tea.Cmd {
+	return func() tea.Msg {
+		return CommandChosenMsg{Command: command}
+	}
+}
+
+func CancelCommandPalette() tea.Cmd {
+	return func() tea.Msg {
+		return CommandPaletteCancelledMsg{}
+	}
+}
+
+func Confirm(id string) tea.Cmd {
+	return func() tea.Msg {
+		return ConfirmedMsg{ID: id}
+	}
+}
+
+func CancelConfirm(id string) tea.Cmd {
+	return func() tea.Msg {
+		return ConfirmCancelledMsg{ID: id}
+	}
+}
+
 func TableSelectionChanged(tableName string) tea.Cmd {
 	return func() tea.Msg {
 		return TableSelectedMsg(tableName)
 	}
 }
 
-func ReadOrCreateQueryFile(dbAlias string) tea.Cmd {
+// reads all of the query buffer files for the database, creating a default one if there are none
+func ReadQueryFiles(dbAlias string) tea.Cmd {
 	return func() tea.Msg {
 
-		dir, err := GetOutputDir()
+		dir, err := GetQueryDir(dbAlias)
+		if err != nil {
+			return ErrMsg{err}
+		}
+
+		filenames, err := filepath.Glob(filepath.Join(dir, "*.sql"))
 		if err != nil {
 			return ErrMsg{err}
 		}
-		filename := filepath.Join(dir, fmt.Sprintf("%s.sql", dbAlias))
+		if len(filenames) == 0 {
+			filename := filepath.Join(dir, DefaultQueryBufferName+".sql")
+			f, err := os.Create(filename)
+			if err != nil {
+				return ErrMsg{err}
+			}
+			if err := f.Close(); err != nil {
+				return ErrMsg{err}
+			}
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
 
-		if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
-			_, err := os.Create(filename)
+		msg := QueryFilesReadMsg{}
+		for _, filename := range filenames {
+			contents, err := os.ReadFile(filename)
 			if err != nil {
 				return ErrMsg{err}
 			}
+			msg.Files = append(msg.Files, QueryFileReadMsg{Contents: string(contents), FileName: filename})
 		}
+		return msg
+	}
+}
 
+func ReadQueryFile(filename string) tea.Cmd {
+	return func() tea.Msg {
 		contents, err := os.ReadFile(filename)
 		if err != nil {
 			return ErrMsg{err}
@@ -119,33 +279,276 @@ func ReadOrCreateQueryFile(dbAlias string) tea.Cmd {
 	}
 }
 
-func SaveQueryFile(dbAlias string, contents string) tea.Cmd {
+// creates a new, empty, query buffer file
+func CreateQueryFile(dbAlias string, name string) tea.Cmd {
 	return func() tea.Msg {
 
-		dir, err := GetOutputDir()
+		filename, err := getQueryFilename(dbAlias, name)
 		if err != nil {
 			return ErrMsg{err}
 		}
-		filename := filepath.Join(dir, fmt.Sprintf("%s.sql", dbAlias))
 
-		if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
-			_, err := os.Create(filename)
-			if err != nil {
-				return ErrMsg{err}
+		f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return ErrMsg{fmt.Errorf("a query buffer named '%s' already exists", name)}
 			}
+			return ErrMsg{err}
+		}
+		if err := f.Close(); err != nil {
+			return ErrMsg{err}
+		}
+		return QueryFileReadMsg{FileName: filename}
+	}
+}
+
+func RenameQueryFile(dbAlias string, filename string, name string) tea.Cmd {
+	return func() tea.Msg {
+
+		newFilename, err := getQueryFilename(dbAlias, name)
+		if err != nil {
+			return ErrMsg{err}
+		}
+
+		if _, err := os.Stat(newFilename); err == nil {
+			return ErrMsg{fmt.Errorf("a query buffer named '%s' already exists", name)}
+		}
+
+		if err := os.Rename(filename, newFilename); err != nil {
+			return ErrMsg{err}
+		}
+		return QueryFileRenamedMsg{OldFileName: filename, FileName: newFilename}
+	}
+}
+
+func DeleteQueryFile(filename string) tea.Cmd {
+	return func() tea.Msg {
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return ErrMsg{err}
+		}
+		return QueryFileDeletedMsg{FileName: filename}
+	}
+}
+
+// saves the query file atomically, writing to a temp file and renaming it over the original
+func SaveQueryFile(filename string, contents string) tea.Cmd {
+	return func() tea.Msg {
+
+		f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+		if err != nil {
+			return ErrMsg{err}
+		}
+		tmpFilename := f.Name()
+
+		// the temp file is only readable by its owner, the saved file keeps the permissions of the one it replaces
+		mode := os.FileMode(0o644)
+		if info, statErr := os.Stat(filename); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+		err = f.Chmod(mode)
+		if err == nil {
+			err = writeAndClose(f, contents)
+		} else {
+			_ = f.Close()
 		}
+		if err == nil {
+			err = os.Rename(tmpFilename, filename)
+		}
+		if err != nil {
+			_ = os.Remove(tmpFilename)
+			return ErrMsg{fmt.Errorf("error saving query file: %w", err)}
+		}
+		return QueryFileSavedMsg{FileName: filename, Contents: contents}
+	}
+}
 
-		f, err := os.Create(filename)
+func writeAndClose(f *os.File, contents string) error {
+	_, err := f.WriteString(contents)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// starts watching the database's query folder for changes made outside of qrypad
+func WatchQueryFiles(dbAlias string) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := GetQueryDir(dbAlias)
 		if err != nil {
 			return ErrMsg{err}
 		}
 
-		_, err = f.WriteString(contents)
+		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
 			return ErrMsg{err}
 		}
-		return QueryFileSavedMsg{}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return ErrMsg{err}
+		}
+		return QueryFileWatcherStartedMsg{Watcher: watcher}
+	}
+}
+
+// stops watching the query folder, the watcher is nil when it couldn't be started
+func CloseQueryFileWatcher(watcher *fsnotify.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		if watcher != nil {
+			_ = watcher.Close()
+		}
+		return nil
+	}
+}
+
+// waits for the next change to a query file, this needs calling again after each change
+func WaitForQueryFileChange(watcher *fsnotify.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+				if filepath.Ext(event.Name) != ".sql" || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				contents, err := os.ReadFile(event.Name)
+				if err != nil {
+					continue
+				}
+				return QueryFileChangedMsg{FileName: event.Name, Contents: string(contents)}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				return QueryFileWatchErrMsg{ErrMsg{err}}
+			}
+		}
+	}
+}
+
+func getLayoutFilename() string {
+	return filepath.Join(filepath.Dir(viper.ConfigFileUsed()), layoutFileName)
+}
+
+// reads the saved panel layout, there is no message when it hasn't been saved yet
+func ReadLayout() tea.Cmd {
+	return func() tea.Msg {
+		contents, err := os.ReadFile(getLayoutFilename())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return ErrMsg{fmt.Errorf("error reading the panel layout: %w", err)}
+		}
+		var layout Layout
+		if err := json.Unmarshal(contents, &layout); err != nil {
+			return ErrMsg{fmt.Errorf("error reading the panel layout from %s: %w", getLayoutFilename(), err)}
+		}
+		return LayoutReadMsg{Layout: layout}
+	}
+}
+
+func SaveLayout(layout Layout) tea.Cmd {
+	return func() tea.Msg {
+		contents, err := json.MarshalIndent(layout, "", "  ")
+		if err == nil {
+			err = os.WriteFile(getLayoutFilename(), contents, 0644)
+		}
+		if err != nil {
+			return ErrMsg{fmt.Errorf("error saving the panel layout: %w", err)}
+		}
+		return nil
+	}
+}
+
+// copies the text to the system clipboard, or when there isn't one (e.g. over ssh)
+// asks the terminal to copy it with an OSC 52 escape sequence, which is drawn with the next frame
+// so it doesn't interleave with the rest of the output
+func CopyToClipboard(text string, description string) tea.Cmd {
+	return func() tea.Msg {
+		err := clipboard.WriteAll(text)
+		if err == nil {
+			return CopiedMsg{Description: description}
+		}
+		if info, statErr := os.Stdout.Stat(); statErr != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return ErrMsg{fmt.Errorf("couldn't copy %s, there's no clipboard or terminal to copy to: %w", description, err)}
+		}
+		seq := osc52.New(text)
+		if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		return TerminalCopyMsg{Sequence: seq.String(), Description: description}
+	}
+}
+
+// clears the OSC 52 sequence once it has been drawn
+func ExpireTerminalCopy() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		return TerminalCopyDrawnMsg{}
+	})
+}
+
+func ExpireToast(id int, after time.Duration) tea.Cmd {
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		return ToastExpiredMsg{ID: id}
+	})
+}
+
+func ExpireStatusMessage(id int, after time.Duration) tea.Cmd {
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		return StatusMessageExpiredMsg{ID: id}
+	})
+}
+
+func Autosave(interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return AutosaveMsg{}
+	})
+}
+
+// returns the folder holding the query buffers for the database, moving any
+// query file from before buffers were supported (<alias>.sql) into it
+func GetQueryDir(dbAlias string) (string, error) {
+	outputDir, err := GetOutputDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(outputDir, dbAlias)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return "", fmt.Errorf("error creating folder to hold query files: %w", err)
+		}
+
+		legacyFilename := filepath.Join(outputDir, fmt.Sprintf("%s.sql", dbAlias))
+		if _, err := os.Stat(legacyFilename); err == nil {
+			err = os.Rename(legacyFilename, filepath.Join(dir, DefaultQueryBufferName+".sql"))
+			if err != nil {
+				return "", fmt.Errorf("error moving query file into buffer folder: %w", err)
+			}
+		}
+	}
+
+	return dir, nil
+}
+
+func getQueryFilename(dbAlias string, name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".sql")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("'%s' is not a valid query buffer name", name)
+	}
+
+	dir, err := GetQueryDir(dbAlias)
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(dir, name+".sql"), nil
 }
 
 func GetOutputDir() (string, error) {
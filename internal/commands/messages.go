@@ -1,5 +1,11 @@
 package commands
 
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/wheelibin/qrypad/internal/keys"
+)
+
 // all command errors are passed back using this
 type ErrMsg struct{ Err error }
 
@@ -17,11 +23,89 @@ type TableSelectedMsg string
 // the details of the query file
 type QueryFileReadMsg struct{ FileName, Contents string }
 
-// sent when the query file has been saved
-type QueryFileSavedMsg struct{}
+// the details of all the query buffer files for the database
+type QueryFilesReadMsg struct{ Files []QueryFileReadMsg }
+
+// sent when the query file has been saved, with the contents that were written
+type QueryFileSavedMsg struct{ FileName, Contents string }
+
+// sent when a query file has been changed on disk, by something other than qrypad
+type QueryFileChangedMsg struct{ FileName, Contents string }
+
+// sent once the query folder is being watched for changes
+type QueryFileWatcherStartedMsg struct{ Watcher *fsnotify.Watcher }
+
+// sent when a statement from the query panel fails, with the query as it was sent
+type StatementErrMsg struct {
+	ErrMsg
+	Query string
+}
+
+// sent when the saved panel layout has been read
+type LayoutReadMsg struct{ Layout Layout }
+
+// sent when watching the query folder fails, watching continues afterwards
+type QueryFileWatchErrMsg struct{ ErrMsg }
+
+// sent when it's time to autosave the query buffers
+type AutosaveMsg struct{}
+
+// sent when a query buffer file has been renamed
+type QueryFileRenamedMsg struct{ OldFileName, FileName string }
+
+// sent when a query buffer file has been deleted
+type QueryFileDeletedMsg struct{ FileName string }
 
 // sent when the user navigates to another tab in the table info panel
 type TableInfoTabChangedMsg int
 
 // fired when external editor is closed
 type EditorFinishedMsg struct{ err error }
+
+// sent when the user submits a form popup, values are keyed by field name
+type FormSubmittedMsg struct {
+	ID     string
+	Values map[string]string
+}
+
+// sent when the user closes a form popup without submitting
+type FormCancelledMsg struct{ ID string }
+
+// sent when the user picks a command in the command palette
+type CommandChosenMsg struct{ Command keys.Command }
+
+// sent when the user closes the command palette without picking a command
+type CommandPaletteCancelledMsg struct{}
+
+// the key of a command picked in the command palette, handled as if it was pressed but never typed into the query
+type CommandKeyMsg tea.KeyMsg
+
+// sent when the user answers yes in a confirmation popup
+type ConfirmedMsg struct{ ID string }
+
+// sent when the user answers no in a confirmation popup
+type ConfirmCancelledMsg struct{ ID string }
+
+// sent when a transaction has been started, committed or rolled back
+type TransactionChangedMsg struct{ Notice string }
+
+// sent when a generated statement, such as a row edit, has been run
+type RowStatementExecutedMsg struct{}
+
+// sent when a status bar message has been shown for long enough
+type StatusMessageExpiredMsg struct{ ID int }
+
+// sent when a toast notification has been shown for long enough
+type ToastExpiredMsg struct{ ID int }
+
+// sent when text has been copied to the clipboard
+type CopiedMsg struct{ Description string }
+
+// sent when there's no system clipboard, so the text is copied by drawing the OSC 52 sequence with the view
+type TerminalCopyMsg struct {
+	Sequence    string
+	Description string
+}
+
+// sent when the OSC 52 sequence has been drawn and can be removed from the view
+type TerminalCopyDrawnMsg struct{}
@@ -0,0 +1,400 @@
+package component
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wrap"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+// postgres returns bytea values in hex format
+var byteaHexRe = regexp.MustCompile(`^\\x([0-9a-fA-F]{2})*$`)
+
+// a line of the value as it is shown, after folding and wrapping
+type cellRow struct {
+	text string
+	// the index of the (unwrapped) line the row is part of
+	line int
+}
+
+type CellViewerPopupModel struct {
+	width  int
+	height int
+	title  string
+	kind   string
+	lines  []string
+	// the line index of the matching close bracket for each json line that opens a block
+	blocks    map[int]int
+	folded    map[int]bool
+	rows      []cellRow
+	cursor    int
+	offset    int
+	search    textinput.Model
+	searching bool
+	matches   []int
+}
+
+func NewCellViewerPopupModel() CellViewerPopupModel {
+	search := textinput.New()
+	search.Prompt = "/"
+	return CellViewerPopupModel{search: search, blocks: map[int]int{}, folded: map[int]bool{}}
+}
+
+func (m CellViewerPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m CellViewerPopupModel) Update(msg tea.Msg) (CellViewerPopupModel, tea.Cmd) {
+	// log.Println("cellViewerPopup.model::Update", msg)
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.searching {
+			switch {
+			case key.Matches(msg, keys.DefaultKeyMap.SubmitForm):
+				m.searching = false
+				m.search.Blur()
+				m.findMatches()
+				m.nextMatch(1)
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup):
+				m.searching = false
+				m.search.Blur()
+				return m, nil
+			}
+			m.search, cmd = m.search.Update(msg)
+			return m, cmd
+		}
+
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.TreeUp):
+			m.cursor = max(m.cursor-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeDown):
+			m.cursor = min(m.cursor+1, len(m.rows)-1)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageUp):
+			m.cursor = max(m.cursor-m.viewHeight(), 0)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageDown):
+			m.cursor = min(m.cursor+m.viewHeight(), len(m.rows)-1)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeToggle):
+			if len(m.rows) > 0 {
+				line := m.rows[m.cursor].line
+				if _, ok := m.blocks[line]; ok {
+					m.folded[line] = !m.folded[line]
+					m.buildRows()
+					m.cursorToLine(line)
+				}
+			}
+		case key.Matches(msg, keys.DefaultKeyMap.SearchValue):
+			m.searching = true
+			m.search.SetValue("")
+			m.search.Focus()
+			return m, textinput.Blink
+		case key.Matches(msg, keys.DefaultKeyMap.NextMatch):
+			m.nextMatch(1)
+		case key.Matches(msg, keys.DefaultKeyMap.PrevMatch):
+			m.nextMatch(-1)
+		}
+		m.scrollToCursor()
+	}
+	return m, nil
+}
+
+// reports whether the search input has focus
+func (m CellViewerPopupModel) IsSearching() bool {
+	return m.searching
+}
+
+// shows the value, formatted according to what it contains
+func (m *CellViewerPopupModel) SetValue(name string, value any) {
+	m.title = name
+	m.kind, m.lines = formatCellValue(value)
+	m.blocks = findBlocks(m.lines)
+	m.folded = map[int]bool{}
+	m.search.SetValue("")
+	m.searching = false
+	m.matches = nil
+	m.cursor = 0
+	m.offset = 0
+	m.buildRows()
+}
+
+// works out how to show the value: json and xml are pretty printed, binary is hex dumped
+func formatCellValue(value any) (string, []string) {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	default:
+		raw = []byte(fmt.Sprintf("%v", v))
+	}
+	text := string(raw)
+
+	if byteaHexRe.MatchString(text) {
+		if b, err := hex.DecodeString(text[2:]); err == nil {
+			return "binary", splitLines(hex.Dump(b))
+		}
+	}
+	if !utf8.Valid(raw) || strings.IndexFunc(text, isBinaryRune) >= 0 {
+		return "binary", splitLines(hex.Dump(raw))
+	}
+
+	trimmed := strings.TrimSpace(text)
+	if pretty, ok := prettyJSON(trimmed); ok {
+		return "json", splitLines(pretty)
+	}
+	// json that has been stored as a string, e.g. "{\"a\": 1}"
+	var inner string
+	if json.Unmarshal([]byte(trimmed), &inner) == nil {
+		if pretty, ok := prettyJSON(strings.TrimSpace(inner)); ok {
+			return "json (decoded from a string)", splitLines(pretty)
+		}
+	}
+	if pretty, ok := prettyXML(trimmed); ok {
+		return "xml", splitLines(pretty)
+	}
+	return "text", splitLines(text)
+}
+
+func isBinaryRune(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
+
+// pretty prints json objects and arrays
+func prettyJSON(s string) (string, bool) {
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return "", false
+	}
+	var b bytes.Buffer
+	if err := json.Indent(&b, []byte(s), "", "  "); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func prettyXML(s string) (string, bool) {
+	if !strings.HasPrefix(s, "<") {
+		return "", false
+	}
+	var b bytes.Buffer
+	decoder := xml.NewDecoder(strings.NewReader(s))
+	encoder := xml.NewEncoder(&b)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false
+		}
+		// whitespace between elements is replaced by the indentation
+		if data, ok := token.(xml.CharData); ok && len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := encoder.EncodeToken(token); err != nil {
+			return "", false
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// pairs the lines that open a json object or array with the lines that close them
+func findBlocks(lines []string) map[int]int {
+	blocks := map[int]int{}
+	var open []int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(open) > 0 && (strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]")) {
+			blocks[open[len(open)-1]] = i
+			open = open[:len(open)-1]
+		}
+		if strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, "[") {
+			open = append(open, i)
+		}
+	}
+	return blocks
+}
+
+// builds the rows that are shown from the lines, skipping folded blocks and wrapping long lines
+func (m *CellViewerPopupModel) buildRows() {
+	m.rows = nil
+	width := max(m.width-4, 1)
+	for i := 0; i < len(m.lines); i++ {
+		line := m.lines[i]
+		if end, ok := m.blocks[i]; ok && m.folded[i] {
+			line += fmt.Sprintf(" … %d lines %s", end-i-1, strings.TrimSpace(m.lines[end]))
+			m.rows = append(m.rows, cellRow{text: truncate.StringWithTail(line, uint(width), "…"), line: i})
+			i = end
+			continue
+		}
+		for _, r := range strings.Split(wrap.String(strings.ReplaceAll(line, "\t", "    "), width), "\n") {
+			m.rows = append(m.rows, cellRow{text: r, line: i})
+		}
+	}
+	m.cursor = min(m.cursor, max(len(m.rows)-1, 0))
+}
+
+func (m *CellViewerPopupModel) cursorToLine(line int) {
+	for i, r := range m.rows {
+		if r.line == line {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// finds the rows containing the search text (ignoring case)
+func (m *CellViewerPopupModel) findMatches() {
+	m.matches = nil
+	term := strings.ToLower(m.search.Value())
+	if term == "" {
+		return
+	}
+	// unfold everything so matches inside folded blocks can be shown
+	m.folded = map[int]bool{}
+	m.buildRows()
+	for i, r := range m.rows {
+		if strings.Contains(strings.ToLower(r.text), term) {
+			m.matches = append(m.matches, i)
+		}
+	}
+}
+
+// moves the cursor to the next (1) or previous (-1) match, wrapping around
+func (m *CellViewerPopupModel) nextMatch(dir int) {
+	if len(m.matches) == 0 {
+		return
+	}
+	if dir > 0 {
+		for _, i := range m.matches {
+			if i > m.cursor {
+				m.cursor = i
+				return
+			}
+		}
+		m.cursor = m.matches[0]
+		return
+	}
+	for j := len(m.matches) - 1; j >= 0; j-- {
+		if m.matches[j] < m.cursor {
+			m.cursor = m.matches[j]
+			return
+		}
+	}
+	m.cursor = m.matches[len(m.matches)-1]
+}
+
+func (m *CellViewerPopupModel) scrollToCursor() {
+	visible := m.viewHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+}
+
+// the number of rows that fit, leaving space for the title and help
+func (m CellViewerPopupModel) viewHeight() int {
+	return max(m.height-4, 1)
+}
+
+func (m *CellViewerPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.search.Width = max(w-6, 1)
+	m.buildRows()
+	m.scrollToCursor()
+}
+
+// highlights each occurrence of the search text in the row
+func (m CellViewerPopupModel) highlight(text string, base lipgloss.Style) string {
+	term := m.search.Value()
+	lower, lowerTerm := strings.ToLower(text), strings.ToLower(term)
+	// lower casing can change the length of some characters, so the indexes wouldn't match
+	if term == "" || m.searching || len(lower) != len(text) || len(lowerTerm) != len(term) {
+		return base.Render(text)
+	}
+	matchStyle := base.Background(colour.CellViewerMatchBG).Foreground(colour.PanelTitleActiveFG)
+
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, lowerTerm)
+		if i < 0 {
+			b.WriteString(base.Render(text))
+			break
+		}
+		b.WriteString(base.Render(text[:i]))
+		b.WriteString(matchStyle.Render(text[i : i+len(term)]))
+		text, lower = text[i+len(term):], lower[i+len(term):]
+	}
+	return b.String()
+}
+
+func (m CellViewerPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.CellViewerPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.CellViewerPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("%s (%s)", m.title, m.kind))
+
+	lineStyle := lipgloss.NewStyle()
+	cursorStyle := lineStyle.Background(colour.ExplainSelectedBG).Foreground(colour.PanelTitleActiveFG)
+
+	var lines []string
+	end := min(m.offset+m.viewHeight(), len(m.rows))
+	for i := m.offset; i < end; i++ {
+		s := lineStyle
+		if i == m.cursor {
+			s = cursorStyle
+		}
+		lines = append(lines, m.highlight(m.rows[i].text, s))
+	}
+	content := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Height(m.viewHeight()).
+		Render(strings.Join(lines, "\n"))
+
+	footer := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("↑/↓ move  enter fold  / search  n/N next/prev match  esc close")
+	if m.searching {
+		footer = lipgloss.NewStyle().PaddingLeft(1).Render(m.search.View())
+	} else if m.search.Value() != "" {
+		footer = lipgloss.NewStyle().
+			Foreground(colour.HelpKey).
+			PaddingLeft(1).
+			Render(fmt.Sprintf("%d matches for '%s'  n/N next/prev  / search  esc close", len(m.matches), m.search.Value()))
+	}
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, content, footer))
+}
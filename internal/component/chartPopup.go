@@ -0,0 +1,504 @@
+package component
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+type ChartKind string
+
+const (
+	ChartKindBar       ChartKind = "bar"
+	ChartKindLine      ChartKind = "line"
+	ChartKindSparkline ChartKind = "sparkline"
+)
+
+var chartKinds = []ChartKind{ChartKindBar, ChartKindLine, ChartKindSparkline}
+
+// the layouts a label is tried against to see if it's a date
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05.999999Z07",
+	"2006-01-02 15:04:05.999999",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// the eighths of a block, for the ends of bars
+var barEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// the braille dot bits, by dot column then row
+var brailleDots = [2][4]rune{{0x01, 0x02, 0x04, 0x40}, {0x08, 0x10, 0x20, 0x80}}
+
+// a numeric column, with NaN where a row has no number
+type chartSeries struct {
+	name   string
+	values []float64
+}
+
+type ChartPopupModel struct {
+	width  int
+	height int
+	kind   ChartKind
+	label  string
+	labels []string
+	// the label of each row as a time, nil unless they are all dates
+	times  []time.Time
+	series []chartSeries
+	offset int
+}
+
+func NewChartPopupModel() ChartPopupModel {
+	return ChartPopupModel{kind: ChartKindBar}
+}
+
+func (m ChartPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ChartPopupModel) Update(msg tea.Msg) (ChartPopupModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.NextChartType):
+			i := slices.Index(chartKinds, m.kind)
+			m.kind = chartKinds[(i+1)%len(chartKinds)]
+			m.offset = 0
+		case key.Matches(msg, keys.DefaultKeyMap.TreeUp):
+			m.offset = max(m.offset-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeDown):
+			if m.kind == ChartKindBar {
+				m.offset = min(m.offset+1, max(len(m.labels)*len(m.series)-m.plotHeight(), 0))
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *ChartPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// the chart kind for a name, as typed in the chart form
+func ParseChartKind(s string) (ChartKind, bool) {
+	kind := ChartKind(strings.ToLower(strings.TrimSpace(s)))
+	return kind, slices.Contains(chartKinds, kind)
+}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func chartNumber(v any) float64 {
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// picks the columns to chart when none have been chosen: the first column against the numeric
+// columns, as a time series line when the first column is a date
+func DefaultChart(columns []string, rows []map[string]any) (string, []string, ChartKind) {
+	if len(columns) == 0 {
+		return "", nil, ChartKindBar
+	}
+	label := columns[0]
+	var series []string
+	for _, c := range columns[1:] {
+		numeric, found := true, false
+		for _, row := range rows {
+			if v := fmt.Sprintf("%v", row[c]); v != "NULL" {
+				found = true
+				numeric = numeric && !math.IsNaN(chartNumber(v))
+			}
+		}
+		if numeric && found {
+			series = append(series, c)
+		}
+	}
+
+	kind := ChartKindBar
+	if len(rows) > 0 {
+		if _, ok := parseDate(fmt.Sprintf("%v", rows[0][label])); ok {
+			kind = ChartKindLine
+		}
+	}
+	return label, series, kind
+}
+
+// sets the rows to chart, the label column against the value columns
+func (m *ChartPopupModel) SetChart(label string, values []string, rows []map[string]any, kind ChartKind) error {
+	if len(values) == 0 {
+		return fmt.Errorf("there are no numeric columns to chart")
+	}
+	m.kind = kind
+	m.label = label
+	m.offset = 0
+	m.labels = nil
+	m.times = nil
+	m.series = nil
+
+	allDates := true
+	for _, row := range rows {
+		l := fmt.Sprintf("%v", row[label])
+		m.labels = append(m.labels, l)
+		t, ok := parseDate(l)
+		allDates = allDates && ok
+		m.times = append(m.times, t)
+	}
+	if !allDates || len(rows) == 0 {
+		m.times = nil
+	}
+
+	for _, col := range values {
+		s := chartSeries{name: col}
+		found := false
+		for _, row := range rows {
+			n := chartNumber(row[col])
+			found = found || !math.IsNaN(n)
+			s.values = append(s.values, n)
+		}
+		if !found {
+			return fmt.Errorf("'%s' has no numbers to chart", col)
+		}
+		m.series = append(m.series, s)
+	}
+	return nil
+}
+
+func (m ChartPopupModel) plotWidth() int {
+	return max(m.width-4, 10)
+}
+
+// the lines left for the chart, after the title, legend and help
+func (m ChartPopupModel) plotHeight() int {
+	return max(m.height-4, 3)
+}
+
+func seriesColour(i int) lipgloss.TerminalColor {
+	return colour.ChartSeries[i%len(colour.ChartSeries)]
+}
+
+// the smallest and largest numbers across the series
+func (m ChartPopupModel) valueRange() (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range m.series {
+		for _, v := range s.values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	return lo, hi
+}
+
+func (m ChartPopupModel) barChart() string {
+	_, hi := m.valueRange()
+	width := m.plotWidth()
+
+	labelWidth, valueWidth := 1, 1
+	for _, l := range m.labels {
+		labelWidth = max(labelWidth, lipgloss.Width(l))
+	}
+	labelWidth = min(labelWidth, width/4)
+	for _, s := range m.series {
+		for _, v := range s.values {
+			valueWidth = max(valueWidth, len(formatStat(v)))
+		}
+	}
+	barWidth := max(width-labelWidth-valueWidth-2, 1)
+
+	labelStyle := lipgloss.NewStyle().Foreground(colour.ChartAxis).Width(labelWidth).MaxWidth(labelWidth)
+	var lines []string
+	for i, l := range m.labels {
+		for si, s := range m.series {
+			text := ""
+			if si == 0 {
+				text = fitText(l, labelWidth)
+			}
+			v := s.values[i]
+			bar := ""
+			if !math.IsNaN(v) && v > 0 && hi > 0 {
+				eighths := int(math.Round(v / hi * float64(barWidth*8)))
+				bar = strings.Repeat("█", eighths/8) + barEighths[eighths%8]
+			}
+			value := "NULL"
+			if !math.IsNaN(v) {
+				value = formatStat(v)
+			}
+			lines = append(lines, labelStyle.Render(text)+" "+
+				lipgloss.NewStyle().Foreground(seriesColour(si)).Render(bar)+" "+value)
+		}
+	}
+
+	end := min(m.offset+m.plotHeight(), len(lines))
+	return strings.Join(lines[min(m.offset, end):end], "\n")
+}
+
+// the x position of each point, from 0 to 1, spaced by time for dates
+func (m ChartPopupModel) xPositions() []float64 {
+	n := len(m.labels)
+	var first, last time.Time
+	if m.times != nil {
+		first = slices.MinFunc(m.times, func(a, b time.Time) int { return a.Compare(b) })
+		last = slices.MaxFunc(m.times, func(a, b time.Time) int { return a.Compare(b) })
+	}
+	xs := make([]float64, n)
+	for i := range xs {
+		switch {
+		case n == 1:
+			xs[i] = 0.5
+		case last.After(first):
+			xs[i] = float64(m.times[i].Sub(first)) / float64(last.Sub(first))
+		default:
+			xs[i] = float64(i) / float64(n-1)
+		}
+	}
+	return xs
+}
+
+func (m ChartPopupModel) lineChart() string {
+	lo, hi := m.valueRange()
+	top, bottom, mid := formatStat(hi), formatStat(lo), formatStat((hi+lo)/2)
+	axisWidth := max(len(top), len(bottom), len(mid))
+
+	cols := max(m.plotWidth()-axisWidth-1, 1)
+	rows := max(m.plotHeight()-1, 1)
+	dotsX, dotsY := cols*2, rows*4
+
+	cells := make([][]rune, rows)
+	cellSeries := make([][]int, rows)
+	for r := range cells {
+		cells[r] = make([]rune, cols)
+		cellSeries[r] = make([]int, cols)
+	}
+	plot := func(x, y, si int) {
+		if x < 0 || y < 0 || x >= dotsX || y >= dotsY {
+			return
+		}
+		cells[y/4][x/2] |= brailleDots[x%2][y%4]
+		cellSeries[y/4][x/2] = si
+	}
+
+	xs := m.xPositions()
+	for si, s := range m.series {
+		prevX, prevY, havePrev := 0, 0, false
+		for i, v := range s.values {
+			if math.IsNaN(v) {
+				havePrev = false
+				continue
+			}
+			x := int(math.Round(xs[i] * float64(dotsX-1)))
+			y := dotsY / 2
+			if hi > lo {
+				y = int(math.Round((hi - v) / (hi - lo) * float64(dotsY-1)))
+			}
+			if havePrev {
+				drawLine(prevX, prevY, x, y, func(px, py int) { plot(px, py, si) })
+			} else {
+				plot(x, y, si)
+			}
+			prevX, prevY, havePrev = x, y, true
+		}
+	}
+
+	axisStyle := lipgloss.NewStyle().Foreground(colour.ChartAxis)
+	var lines []string
+	for r := range cells {
+		axis := ""
+		switch r {
+		case 0:
+			axis = top
+		case rows / 2:
+			axis = mid
+		case rows - 1:
+			axis = bottom
+		}
+		var b strings.Builder
+		for c, bits := range cells[r] {
+			if bits == 0 {
+				b.WriteString(" ")
+				continue
+			}
+			b.WriteString(lipgloss.NewStyle().Foreground(seriesColour(cellSeries[r][c])).Render(string(0x2800 + bits)))
+		}
+		lines = append(lines, axisStyle.Render(fmt.Sprintf("%*s┤", axisWidth, axis))+b.String())
+	}
+
+	// the labels of the leftmost and rightmost points under the ends of the x axis
+	first, last := m.labels[slices.Index(xs, slices.Min(xs))], m.labels[slices.Index(xs, slices.Max(xs))]
+	gap := cols - lipgloss.Width(first) - lipgloss.Width(last)
+	xAxis := first
+	if gap > 0 && len(m.labels) > 1 {
+		xAxis += strings.Repeat(" ", gap) + last
+	}
+	lines = append(lines, axisStyle.Render(strings.Repeat(" ", axisWidth+1)+truncate.String(xAxis, uint(cols))))
+	return strings.Join(lines, "\n")
+}
+
+// calls plot for each point of a line between the two points (Bresenham's algorithm)
+func drawLine(x0, y0, x1, y1 int, plot func(x, y int)) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		plot(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// truncates text that is wider than the width, with an ellipsis
+func fitText(text string, width int) string {
+	if lipgloss.Width(text) <= width {
+		return text
+	}
+	return truncate.StringWithTail(text, uint(width), "…")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (m ChartPopupModel) sparklines() string {
+	width := m.plotWidth()
+	nameWidth := 1
+	for _, s := range m.series {
+		nameWidth = max(nameWidth, lipgloss.Width(s.name))
+	}
+	nameWidth = min(nameWidth, width/4)
+
+	var lines []string
+	for si, s := range m.series {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range s.values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+		valueRange := fmt.Sprintf("%s – %s", formatStat(lo), formatStat(hi))
+		sparkWidth := max(width-nameWidth-lipgloss.Width(valueRange)-2, 1)
+
+		var b strings.Builder
+		for _, v := range resample(s.values, sparkWidth) {
+			switch {
+			case math.IsNaN(v):
+				b.WriteRune(' ')
+			case hi == lo:
+				b.WriteRune(sparkTicks[len(sparkTicks)/2])
+			default:
+				b.WriteRune(sparkTicks[int(math.Round((v-lo)/(hi-lo)*float64(len(sparkTicks)-1)))])
+			}
+		}
+
+		name := lipgloss.NewStyle().Foreground(colour.ChartAxis).Width(nameWidth).MaxWidth(nameWidth).
+			Render(fitText(s.name, nameWidth))
+		lines = append(lines, name+" "+lipgloss.NewStyle().Foreground(seriesColour(si)).Render(b.String())+" "+valueRange, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// averages the values into at most n buckets, so a long series fits the width
+func resample(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	out := make([]float64, n)
+	for i := range out {
+		from, to := i*len(values)/n, (i+1)*len(values)/n
+		sum, count := 0.0, 0
+		for _, v := range values[from:to] {
+			if !math.IsNaN(v) {
+				sum += v
+				count++
+			}
+		}
+		out[i] = math.NaN()
+		if count > 0 {
+			out[i] = sum / float64(count)
+		}
+	}
+	return out
+}
+
+func (m ChartPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.ChartPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.ChartPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("%s chart by %s", m.kind, m.label))
+
+	var chart string
+	switch {
+	case len(m.labels) == 0:
+		chart = "no rows to chart"
+	case m.kind == ChartKindLine:
+		chart = m.lineChart()
+	case m.kind == ChartKindSparkline:
+		chart = m.sparklines()
+	default:
+		chart = m.barChart()
+	}
+	chart = lipgloss.NewStyle().PaddingLeft(1).Height(m.plotHeight()).MaxHeight(m.plotHeight()).Render(chart)
+
+	var legend []string
+	for si, s := range m.series {
+		legend = append(legend, lipgloss.NewStyle().Foreground(seriesColour(si)).Render("■")+" "+s.name)
+	}
+	legendView := lipgloss.NewStyle().PaddingLeft(1).Render(truncate.StringWithTail(strings.Join(legend, "  "), uint(m.plotWidth()), "…"))
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("t chart type  ↑/↓ scroll  esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, chart, legendView, help))
+}
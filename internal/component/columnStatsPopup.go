@@ -0,0 +1,99 @@
+package component
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/db"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+type ColumnStatsPopupModel struct {
+	width  int
+	height int
+	stats  db.ColumnStats
+}
+
+func NewColumnStatsPopupModel() ColumnStatsPopupModel {
+	return ColumnStatsPopupModel{}
+}
+
+func (m ColumnStatsPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ColumnStatsPopupModel) Update(msg tea.Msg) (ColumnStatsPopupModel, tea.Cmd) {
+	return m, nil
+}
+
+func (m *ColumnStatsPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+func (m *ColumnStatsPopupModel) SetStats(stats db.ColumnStats) {
+	m.stats = stats
+}
+
+func formatStat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (m ColumnStatsPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		BorderForeground(colour.ColumnStatsPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.ColumnStatsPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("column: %s", m.stats.Column))
+
+	s := m.stats
+	rows := [][2]string{
+		{"rows", strconv.FormatInt(s.Count, 10)},
+		{"nulls", strconv.FormatInt(s.Nulls, 10)},
+		{"distinct", strconv.FormatInt(s.Distinct, 10)},
+		{"min", s.Min},
+		{"max", s.Max},
+	}
+	if s.Numeric {
+		rows = append(rows, [2]string{"mean", formatStat(s.Mean)}, [2]string{"median", formatStat(s.Median)})
+	}
+
+	lineWidth := max(m.width-4, 1)
+	labelStyle := lipgloss.NewStyle().Foreground(colour.FormLabel).Width(10).PaddingLeft(1)
+	var lines []string
+	for _, r := range rows {
+		lines = append(lines, labelStyle.Render(r[0])+truncate.StringWithTail(r[1], uint(max(lineWidth-10, 1)), "…"))
+	}
+
+	if len(s.Top) > 0 {
+		lines = append(lines, "", labelStyle.Render("most frequent values"))
+		countWidth := len(strconv.FormatInt(s.Top[0].Count, 10))
+		for _, vc := range s.Top {
+			count := fmt.Sprintf(" %*d  ", countWidth+1, vc.Count)
+			lines = append(lines, count+truncate.StringWithTail(vc.Value, uint(max(lineWidth-len(count), 1)), "…"))
+		}
+	}
+
+	source := lipgloss.NewStyle().
+		Foreground(colour.ListItemDescFG).
+		PaddingLeft(1).
+		MarginTop(1).
+		Render(fmt.Sprintf("computed from %s", s.Source))
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, strings.Join(lines, "\n"), source, help))
+}
@@ -0,0 +1,208 @@
+package component
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+// a command that matches the filter, with the positions of the matched characters in its name
+type paletteItem struct {
+	command   keys.Command
+	positions []int
+	score     int
+}
+
+type CommandPaletteModel struct {
+	width    int
+	height   int
+	commands []keys.Command
+	filter   textinput.Model
+	items    []paletteItem
+	cursor   int
+	offset   int
+}
+
+func NewCommandPaletteModel() CommandPaletteModel {
+	filter := textinput.New()
+	filter.Prompt = "> "
+	filter.Placeholder = "type to filter the commands"
+	filter.CharLimit = 0
+
+	m := CommandPaletteModel{filter: filter}
+	for _, c := range keys.DefaultKeyMap.Commands() {
+		if c.Panel != keys.PanelPopup {
+			m.commands = append(m.commands, c)
+		}
+	}
+	m.applyFilter()
+	return m
+}
+
+func (m CommandPaletteModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m CommandPaletteModel) Update(msg tea.Msg) (CommandPaletteModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.SubmitForm):
+			if len(m.items) == 0 {
+				return m, nil
+			}
+			return m, commands.ChooseCommand(m.items[m.cursor].command)
+
+		case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup, keys.DefaultKeyMap.CommandPalette):
+			return m, commands.CancelCommandPalette()
+
+		case key.Matches(msg, keys.DefaultKeyMap.PrevField):
+			m.cursor = max(m.cursor-1, 0)
+			m.scrollToCursor()
+			return m, nil
+
+		case key.Matches(msg, keys.DefaultKeyMap.NextField):
+			m.cursor = max(min(m.cursor+1, len(m.items)-1), 0)
+			m.scrollToCursor()
+			return m, nil
+
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageUp):
+			m.cursor = max(m.cursor-m.listHeight(), 0)
+			m.scrollToCursor()
+			return m, nil
+
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageDown):
+			m.cursor = max(min(m.cursor+m.listHeight(), len(m.items)-1), 0)
+			m.scrollToCursor()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	filter := m.filter.Value()
+	m.filter, cmd = m.filter.Update(msg)
+	if m.filter.Value() != filter {
+		m.applyFilter()
+	}
+	return m, cmd
+}
+
+// clears the filter and selects the first command, ready for the palette to be shown
+func (m *CommandPaletteModel) Reset() tea.Cmd {
+	m.filter.SetValue("")
+	m.filter.Focus()
+	m.applyFilter()
+	return textinput.Blink
+}
+
+// lists the commands whose names match the filter, best matches first
+func (m *CommandPaletteModel) applyFilter() {
+	m.items = nil
+	for _, c := range m.commands {
+		if positions, score, ok := fuzzyMatch(m.filter.Value(), c.Name()); ok {
+			m.items = append(m.items, paletteItem{command: c, positions: positions, score: score})
+		}
+	}
+	sort.SliceStable(m.items, func(i, j int) bool { return m.items[i].score > m.items[j].score })
+	m.cursor, m.offset = 0, 0
+}
+
+func (m *CommandPaletteModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.filter.Width = max(w-6, 1)
+	m.scrollToCursor()
+}
+
+func (m *CommandPaletteModel) scrollToCursor() {
+	visible := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+}
+
+// the number of commands that fit, leaving space for the title, the filter and help
+func (m CommandPaletteModel) listHeight() int {
+	return max(m.height-4, 1)
+}
+
+func (m CommandPaletteModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.CommandPaletteTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.CommandPaletteTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("commands (%d)", len(m.items)))
+
+	filter := lipgloss.NewStyle().PaddingLeft(1).Render(m.filter.View())
+
+	keyWidth, groupWidth := 0, 0
+	for _, c := range m.commands {
+		keyWidth = max(keyWidth, lipgloss.Width(c.Binding.Help().Key))
+		groupWidth = max(groupWidth, lipgloss.Width(c.Group))
+	}
+	lineWidth := max(m.width-4, 1)
+	nameWidth := max(lineWidth-keyWidth-groupWidth-2, 1)
+
+	var lines []string
+	end := min(m.offset+m.listHeight(), len(m.items))
+	for i := m.offset; i < end; i++ {
+		item := m.items[i]
+		rowStyle := lipgloss.NewStyle()
+		groupStyle := rowStyle.Foreground(colour.ListItemDescFG)
+		keyStyle := rowStyle.Foreground(colour.HelpKey)
+		matchStyle := rowStyle.Foreground(colour.CommandPaletteMatchFG).Bold(true)
+		if i == m.cursor {
+			rowStyle = rowStyle.Background(colour.CommandPaletteCursorBG).Foreground(colour.PanelTitleActiveFG)
+			groupStyle, keyStyle = rowStyle, rowStyle
+			matchStyle = rowStyle.Bold(true).Underline(true)
+		}
+
+		// every part is styled, so the selected row's background runs the whole width
+		name := []rune(fitText(item.command.Name(), nameWidth))
+		var b strings.Builder
+		matched := map[int]bool{}
+		for _, p := range item.positions {
+			matched[p] = true
+		}
+		for j, r := range name {
+			if matched[j] {
+				b.WriteString(matchStyle.Render(string(r)))
+			} else {
+				b.WriteString(rowStyle.Render(string(r)))
+			}
+		}
+		b.WriteString(rowStyle.Render(strings.Repeat(" ", max(nameWidth-lipgloss.Width(string(name)), 0)+1)))
+		b.WriteString(groupStyle.Width(groupWidth).Render(item.command.Group))
+		b.WriteString(rowStyle.Render(" "))
+		b.WriteString(keyStyle.Width(keyWidth).Align(lipgloss.Right).Render(item.command.Binding.Help().Key))
+		lines = append(lines, b.String())
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no commands match")
+	}
+	list := lipgloss.NewStyle().PaddingLeft(1).Height(m.listHeight()).Render(strings.Join(lines, "\n"))
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("↑/↓ move  enter run  esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, filter, list, help))
+}
@@ -0,0 +1,78 @@
+package component
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+type ConfirmPopupModel struct {
+	width  int
+	height int
+	id     string
+	title  string
+	text   string
+}
+
+func NewConfirmPopupModel() ConfirmPopupModel {
+	return ConfirmPopupModel{}
+}
+
+func (m ConfirmPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ConfirmPopupModel) Update(msg tea.Msg) (ConfirmPopupModel, tea.Cmd) {
+	// log.Println("confirmPopup.model::Update", msg)
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.ConfirmYes):
+			return m, commands.Confirm(m.id)
+		case key.Matches(msg, keys.DefaultKeyMap.ConfirmNo):
+			return m, commands.CancelConfirm(m.id)
+		}
+	}
+	return m, nil
+}
+
+// sets up the popup to ask the question, the id is passed back in the confirm/cancel messages
+func (m *ConfirmPopupModel) SetContent(id string, title string, text string) {
+	m.id = id
+	m.title = title
+	m.text = text
+}
+
+func (m *ConfirmPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+func (m ConfirmPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		BorderForeground(colour.ConfirmPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.ConfirmPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(m.title)
+
+	text := lipgloss.NewStyle().
+		Padding(0, 2).
+		Width(m.width - 2).
+		Render(m.text)
+
+	prompt := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		Padding(1, 2, 0).
+		Render("(y)es / (n)o")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, text, prompt))
+}
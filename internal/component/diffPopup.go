@@ -0,0 +1,200 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/db"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+// the widest a column is shown in the diff
+const maxDiffColumnWidth = 24
+
+type DiffPopupModel struct {
+	width  int
+	height int
+	diff   *db.ResultDiff
+	from   string
+	to     string
+	// the columns after the key, in the order they are shown
+	columns []string
+	widths  map[string]int
+	offset  int
+	// the first column shown after the key, when scrolled horizontally
+	columnOffset int
+}
+
+func NewDiffPopupModel() DiffPopupModel {
+	return DiffPopupModel{}
+}
+
+func (m DiffPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m DiffPopupModel) Update(msg tea.Msg) (DiffPopupModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.diff == nil {
+			return m, nil
+		}
+		maxOffset := max(len(m.lines())-m.listHeight(), 0)
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.TreeUp):
+			m.offset = max(m.offset-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeDown):
+			m.offset = min(m.offset+1, maxOffset)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageUp):
+			m.offset = max(m.offset-m.listHeight(), 0)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageDown):
+			m.offset = min(m.offset+m.listHeight(), maxOffset)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeCollapse):
+			m.columnOffset = max(m.columnOffset-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeExpand):
+			m.columnOffset = min(m.columnOffset+1, max(len(m.columns)-1, 0))
+		}
+	}
+	return m, nil
+}
+
+func (m *DiffPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+// sets the differences to show, from and to describe the two results
+func (m *DiffPopupModel) SetDiff(diff *db.ResultDiff, from, to string) {
+	m.diff = diff
+	m.from = from
+	m.to = to
+	m.offset = 0
+	m.columnOffset = 0
+
+	m.columns = nil
+	m.widths = map[string]int{}
+	for _, c := range diff.Columns {
+		if c != diff.Key {
+			m.columns = append(m.columns, c)
+		}
+		m.widths[c] = lipgloss.Width(c)
+	}
+	for _, r := range diff.Rows {
+		for _, row := range []map[string]any{r.From, r.To} {
+			for c, v := range row {
+				m.widths[c] = max(m.widths[c], lipgloss.Width(fmt.Sprintf("%v", v)))
+			}
+		}
+	}
+	for c, w := range m.widths {
+		m.widths[c] = min(w, maxDiffColumnWidth)
+	}
+}
+
+// the lines left for the rows, after the title, summary, header and help
+func (m DiffPopupModel) listHeight() int {
+	return max(m.height-5, 1)
+}
+
+// renders a row's cells, highlighting those that changed
+func (m DiffPopupModel) renderRow(marker string, markerStyle lipgloss.Style, row map[string]any, changed map[string]bool) string {
+	cell := func(c string) string {
+		text := "" // the column isn't in this result
+		if v, ok := row[c]; ok {
+			text = fmt.Sprintf("%v", v)
+		}
+		s := lipgloss.NewStyle()
+		if changed[c] {
+			s = s.Background(colour.DiffChangedCellBG).Foreground(colour.DiffChangedCellFG)
+		}
+		return s.Render(fmt.Sprintf("%-*s", m.widths[c], fitText(text, m.widths[c])))
+	}
+
+	cells := []string{markerStyle.Render(marker), cell(m.diff.Key)}
+	for _, c := range m.columns[min(m.columnOffset, len(m.columns)):] {
+		cells = append(cells, cell(c))
+	}
+	return strings.Join(cells, " ")
+}
+
+// the lines for the rows that differ, two for a changed row (before and after)
+func (m DiffPopupModel) lines() []string {
+	added := lipgloss.NewStyle().Foreground(colour.DiffAdded)
+	removed := lipgloss.NewStyle().Foreground(colour.DiffRemoved)
+	changed := lipgloss.NewStyle().Foreground(colour.DiffChanged)
+
+	var lines []string
+	for _, r := range m.diff.Rows {
+		switch r.Status {
+		case db.DiffAdded:
+			lines = append(lines, m.renderRow("+", added, r.To, nil))
+		case db.DiffRemoved:
+			lines = append(lines, m.renderRow("-", removed, r.From, nil))
+		case db.DiffChanged:
+			lines = append(lines,
+				m.renderRow("~", changed, r.From, r.Changed),
+				m.renderRow(" ", changed, r.To, r.Changed))
+		}
+	}
+	return lines
+}
+
+func (m DiffPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.DiffPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.DiffPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(fitText(fmt.Sprintf("diff: %s → %s", m.from, m.to), m.width-4))
+
+	if m.diff == nil {
+		return popupStyle.Render(title)
+	}
+
+	lineWidth := max(m.width-4, 1)
+	summary := lipgloss.NewStyle().PaddingLeft(1).Render(fmt.Sprintf("%s  %s  %s  %d unchanged  (matched on %s)",
+		lipgloss.NewStyle().Foreground(colour.DiffAdded).Render(fmt.Sprintf("%d added", m.diff.Added)),
+		lipgloss.NewStyle().Foreground(colour.DiffRemoved).Render(fmt.Sprintf("%d removed", m.diff.Removed)),
+		lipgloss.NewStyle().Foreground(colour.DiffChanged).Render(fmt.Sprintf("%d changed", m.diff.Changed)),
+		m.diff.Unchanged, m.diff.Key))
+
+	header := m.renderRow(" ", lipgloss.NewStyle(), headerRow(m.diff.Columns), nil)
+	header = lipgloss.NewStyle().Foreground(colour.ChartAxis).Bold(true).Render(fitText(header, lineWidth))
+
+	lines := m.lines()
+	if len(lines) == 0 {
+		lines = []string{"the results are the same"}
+	}
+	end := min(m.offset+m.listHeight(), len(lines))
+	var visible []string
+	for _, l := range lines[min(m.offset, end):end] {
+		visible = append(visible, fitText(l, lineWidth))
+	}
+	list := lipgloss.NewStyle().Height(m.listHeight()).PaddingLeft(1).Render(strings.Join(visible, "\n"))
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("↑/↓ pgup/pgdown scroll  ←/→ scroll columns  esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, summary, lipgloss.NewStyle().PaddingLeft(1).Render(header), list, help))
+}
+
+// a row with the column names as its values, for the header
+func headerRow(columns []string) map[string]any {
+	row := map[string]any{}
+	for _, c := range columns {
+		row[c] = c
+	}
+	return row
+}
@@ -1,16 +1,21 @@
 package component
 
 import (
+	"fmt"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/db"
 	"github.com/wheelibin/qrypad/internal/style"
 )
 
 type ErrorPopupModel struct {
-	width  int
-	height int
-	text   string
+	width   int
+	height  int
+	details db.ErrorDetails
+	// where the error is in the query buffer, nil when the error doesn't say
+	position *ErrorPosition
 }
 
 func NewErrorPopupModel() ErrorPopupModel {
@@ -41,7 +46,55 @@ func (m *ErrorPopupModel) SetSize(w, h int) {
 }
 
 func (m *ErrorPopupModel) SetText(text string) {
-	m.text = text
+	m.SetError(db.ErrorDetails{Message: text})
+}
+
+func (m *ErrorPopupModel) SetError(details db.ErrorDetails) {
+	m.details = details
+	m.position = nil
+}
+
+// shows the line of the query buffer where the error is, with the failing token highlighted
+func (m *ErrorPopupModel) SetPosition(position ErrorPosition) {
+	m.position = &position
+}
+
+// the error as it is copied
+func (m ErrorPopupModel) GetCopyText() string {
+	text := m.details.String()
+	if m.position != nil {
+		text += fmt.Sprintf("\nline %d: %s", m.position.LineNumber, m.position.Line)
+	}
+	if m.details.Code != "" {
+		text += "\n" + m.details.Code
+	}
+	return text
+}
+
+// the error's line with the failing token highlighted, scrolled so the token fits in width
+func (m ErrorPopupModel) errorLineView(width int) string {
+	prefix := fmt.Sprintf("line %d: ", m.position.LineNumber)
+	line := []rune(m.position.Line)
+	start := min(m.position.Column, len(line))
+	end := min(start+max(m.position.Length, 1), len(line))
+
+	available := max(width-lipgloss.Width(prefix), 1)
+	from := min(max(end-available, 0), start)
+	before := string(line[from:start])
+	if from > 0 && from < start {
+		before = "…" + string(line[from+1:start])
+	}
+	token := string(line[start:end])
+	if token == "" {
+		// the error is at the end of the statement
+		token = " "
+	}
+	after := fitText(string(line[end:]), max(available-lipgloss.Width(before)-lipgloss.Width(token), 0))
+
+	return lipgloss.NewStyle().Foreground(colour.ListItemDescFG).Render(prefix) +
+		before +
+		lipgloss.NewStyle().Background(colour.ErrorTokenBG).Foreground(colour.ErrorTokenFG).Render(token) +
+		after
 }
 
 func (m ErrorPopupModel) View() string {
@@ -53,7 +106,25 @@ func (m ErrorPopupModel) View() string {
 		Padding(0, 2).
 		Align(lipgloss.Center).
 		Width(m.width - 2)
-	err := errStyle.Render(m.text)
+	err := errStyle.Render(m.details.Message)
+	textStyle := errStyle.Foreground(lipgloss.NoColor{}).MarginTop(1)
+	if m.details.Detail != "" {
+		err = lipgloss.JoinVertical(lipgloss.Center, err, textStyle.Render("detail: "+m.details.Detail))
+	}
+	if m.details.Hint != "" {
+		err = lipgloss.JoinVertical(lipgloss.Center, err, textStyle.Render("hint: "+m.details.Hint))
+	}
+	if m.position != nil {
+		err = lipgloss.JoinVertical(lipgloss.Center, err, textStyle.Render(m.errorLineView(m.width-6)))
+	}
+	if m.details.Code != "" {
+		err = lipgloss.JoinVertical(lipgloss.Center, err, errStyle.Foreground(colour.ListItemDescFG).MarginTop(1).Render(m.details.Code))
+	}
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		MarginTop(1).
+		Render("y copy  alt+l message log  esc close")
+	err = lipgloss.JoinVertical(lipgloss.Center, err, help)
 
 	errHeight := lipgloss.Height(err)
 	popupStyle = popupStyle.Height(errHeight + 3)
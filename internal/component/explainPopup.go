@@ -0,0 +1,198 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/db"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+// a plan node as it appears in the (expanded) tree
+type planLine struct {
+	node  *db.PlanNode
+	depth int
+}
+
+type ExplainPopupModel struct {
+	width     int
+	height    int
+	plan      *db.Plan
+	hottest   *db.PlanNode
+	collapsed map[*db.PlanNode]bool
+	lines     []planLine
+	cursor    int
+	offset    int
+}
+
+func NewExplainPopupModel() ExplainPopupModel {
+	return ExplainPopupModel{collapsed: map[*db.PlanNode]bool{}}
+}
+
+func (m ExplainPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ExplainPopupModel) Update(msg tea.Msg) (ExplainPopupModel, tea.Cmd) {
+	// log.Println("explainPopup.model::Update", msg)
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if len(m.lines) == 0 {
+			return m, nil
+		}
+		node := m.lines[m.cursor].node
+
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.TreeUp):
+			m.cursor = max(m.cursor-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeDown):
+			m.cursor = min(m.cursor+1, len(m.lines)-1)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeToggle):
+			m.collapsed[node] = !m.collapsed[node]
+		case key.Matches(msg, keys.DefaultKeyMap.TreeCollapse):
+			m.collapsed[node] = true
+		case key.Matches(msg, keys.DefaultKeyMap.TreeExpand):
+			m.collapsed[node] = false
+		}
+		m.buildLines()
+		m.scrollToCursor()
+	}
+	return m, nil
+}
+
+func (m *ExplainPopupModel) SetPlan(plan *db.Plan) {
+	m.plan = plan
+	m.hottest = plan.MostExpensiveNode()
+	m.collapsed = map[*db.PlanNode]bool{}
+	m.cursor = 0
+	m.offset = 0
+	m.buildLines()
+}
+
+// flattens the visible part of the tree into lines
+func (m *ExplainPopupModel) buildLines() {
+	m.lines = nil
+	if m.plan == nil || m.plan.Root == nil {
+		return
+	}
+	var walk func(n *db.PlanNode, depth int)
+	walk = func(n *db.PlanNode, depth int) {
+		m.lines = append(m.lines, planLine{node: n, depth: depth})
+		if m.collapsed[n] {
+			return
+		}
+		for _, c := range n.Children {
+			walk(c, depth+1)
+		}
+	}
+	walk(m.plan.Root, 0)
+	m.cursor = min(m.cursor, len(m.lines)-1)
+}
+
+func (m *ExplainPopupModel) scrollToCursor() {
+	visible := m.treeHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+}
+
+// the number of tree lines that fit, leaving space for the title, details and help
+func (m ExplainPopupModel) treeHeight() int {
+	return max(m.height-12, 1)
+}
+
+func (m *ExplainPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.scrollToCursor()
+}
+
+func (m ExplainPopupModel) formatNode(n *db.PlanNode) string {
+	metrics := fmt.Sprintf("cost=%.2f rows=%.0f", n.Cost, n.Rows)
+	if m.plan.Analyzed {
+		metrics += fmt.Sprintf("  actual time=%.3fms rows=%.0f loops=%.0f", n.ActualTime, n.ActualRows, n.Loops)
+	}
+	return fmt.Sprintf("%s  (%s)", n.Label, metrics)
+}
+
+func (m ExplainPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.ExplainPopupTitleBG)
+
+	titleText := "explain"
+	if m.plan != nil && m.plan.Analyzed {
+		titleText = "explain analyze"
+	}
+	title := style.Title(m.width-2, false).
+		Background(colour.ExplainPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(titleText)
+
+	lineWidth := m.width - 4
+	lineStyle := lipgloss.NewStyle().PaddingLeft(1)
+	hotStyle := lineStyle.Foreground(colour.ExplainHotNode).Bold(true)
+	selectedStyle := lineStyle.Background(colour.ExplainSelectedBG).Foreground(colour.PanelTitleActiveFG)
+
+	var lines []string
+	end := min(m.offset+m.treeHeight(), len(m.lines))
+	for i := m.offset; i < end; i++ {
+		l := m.lines[i]
+		marker := "  "
+		if len(l.node.Children) > 0 {
+			marker = "▾ "
+			if m.collapsed[l.node] {
+				marker = "▸ "
+			}
+		}
+		text := truncate.StringWithTail(strings.Repeat("  ", l.depth)+marker+m.formatNode(l.node), uint(lineWidth), "…")
+
+		s := lineStyle
+		if l.node == m.hottest {
+			s = hotStyle
+		}
+		if i == m.cursor {
+			s = selectedStyle
+		}
+		lines = append(lines, s.Render(text))
+	}
+	tree := lipgloss.NewStyle().Height(m.treeHeight()).Render(strings.Join(lines, "\n"))
+
+	// details of the selected node
+	var details []string
+	if len(m.lines) > 0 {
+		n := m.lines[m.cursor].node
+		details = append(details, n.Label)
+		if n == m.hottest {
+			details = append(details, "(most expensive node)")
+		}
+		for _, d := range n.Details {
+			details = append(details, truncate.StringWithTail(d, uint(lineWidth), "…"))
+		}
+	}
+	detailsView := lipgloss.NewStyle().
+		Foreground(colour.ListItemDescFG).
+		PaddingLeft(1).
+		MarginTop(1).
+		Height(6).
+		MaxHeight(7).
+		Render(strings.Join(details, "\n"))
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("↑/↓ move  enter toggle  ←/→ collapse/expand  esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, tree, detailsView, help))
+}
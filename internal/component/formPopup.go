@@ -0,0 +1,167 @@
+package component
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+type FormField struct {
+	Name        string
+	Label       string
+	Value       string
+	Placeholder string
+}
+
+type FormPopupModel struct {
+	width      int
+	height     int
+	id         string
+	title      string
+	fields     []FormField
+	inputs     []textinput.Model
+	focusIndex int
+}
+
+func NewFormPopupModel() FormPopupModel {
+	return FormPopupModel{}
+}
+
+func (m FormPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m FormPopupModel) Update(msg tea.Msg) (FormPopupModel, tea.Cmd) {
+	// log.Println("formPopup.model::Update", msg)
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.SubmitForm):
+			values := map[string]string{}
+			for i, f := range m.fields {
+				values[f.Name] = m.inputs[i].Value()
+			}
+			return m, commands.SubmitForm(m.id, values)
+
+		case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup):
+			return m, commands.CancelForm(m.id)
+
+		case key.Matches(msg, keys.DefaultKeyMap.NextField):
+			m.setFocus((m.focusIndex + 1) % len(m.inputs))
+			return m, textinput.Blink
+
+		case key.Matches(msg, keys.DefaultKeyMap.PrevField):
+			i := m.focusIndex - 1
+			if i < 0 {
+				i = len(m.inputs) - 1
+			}
+			m.setFocus(i)
+			return m, textinput.Blink
+		}
+	}
+
+	if len(m.inputs) > 0 {
+		m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// sets up the popup to ask for the given fields, the id is passed back in the submit/cancel messages
+func (m *FormPopupModel) SetFields(id string, title string, fields []FormField) tea.Cmd {
+	m.id = id
+	m.title = title
+	m.fields = fields
+	m.inputs = make([]textinput.Model, len(fields))
+	for i, f := range fields {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.CharLimit = 0
+		ti.SetValue(f.Value)
+		ti.Placeholder = f.Placeholder
+		m.inputs[i] = ti
+	}
+	m.setSizes()
+	m.setFocus(0)
+	return textinput.Blink
+}
+
+func (m *FormPopupModel) setFocus(index int) {
+	m.focusIndex = index
+	for i := range m.inputs {
+		if i == index {
+			m.inputs[i].Focus()
+		} else {
+			m.inputs[i].Blur()
+		}
+	}
+}
+
+func (m *FormPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.setSizes()
+}
+
+func (m *FormPopupModel) setSizes() {
+	for i := range m.inputs {
+		m.inputs[i].Width = m.width - m.labelWidth() - 6
+	}
+}
+
+func (m FormPopupModel) labelWidth() int {
+	w := 0
+	for _, f := range m.fields {
+		w = max(w, lipgloss.Width(f.Label))
+	}
+	return w
+}
+
+func (m FormPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		BorderForeground(colour.FormPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.FormPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(m.title)
+
+	labelStyle := lipgloss.NewStyle().
+		Width(m.labelWidth()).
+		MarginLeft(2).
+		MarginRight(1).
+		Foreground(colour.FormLabel)
+
+	// show a window of the fields around the focused one when they don't all fit
+	visible := len(m.fields)
+	if m.height > 0 {
+		visible = min(visible, max(m.height-4, 1))
+	}
+	start := max(m.focusIndex-visible+1, 0)
+
+	lines := []string{title}
+	for i := start; i < start+visible; i++ {
+		f := m.fields[i]
+		label := labelStyle.Render(f.Label)
+		if i == m.focusIndex {
+			label = labelStyle.Foreground(colour.FormLabelActive).Render(f.Label)
+		}
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, label, m.inputs[i].View()))
+	}
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
@@ -0,0 +1,161 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wrap"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/keys"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+// an error or notice from the session
+type LogEntry struct {
+	Time  time.Time
+	Level MessageLevel
+	Text  string
+	// the database error code, if there was one
+	Code string
+}
+
+// the entry as it is copied
+func (e LogEntry) String() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s\n%s", e.Text, e.Code)
+	}
+	return e.Text
+}
+
+type MessageLogPopupModel struct {
+	width   int
+	height  int
+	entries []LogEntry
+	cursor  int
+	offset  int
+}
+
+func NewMessageLogPopupModel() MessageLogPopupModel {
+	return MessageLogPopupModel{}
+}
+
+func (m MessageLogPopupModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m MessageLogPopupModel) Update(msg tea.Msg) (MessageLogPopupModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if len(m.entries) == 0 {
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, keys.DefaultKeyMap.TreeUp):
+			m.cursor = max(m.cursor-1, 0)
+		case key.Matches(msg, keys.DefaultKeyMap.TreeDown):
+			m.cursor = min(m.cursor+1, len(m.entries)-1)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageUp):
+			m.cursor = max(m.cursor-m.listHeight(), 0)
+		case key.Matches(msg, keys.DefaultKeyMap.ViewerPageDown):
+			m.cursor = min(m.cursor+m.listHeight(), len(m.entries)-1)
+		case key.Matches(msg, keys.DefaultKeyMap.Copy):
+			return m, commands.CopyToClipboard(m.entries[m.cursor].String(), "the message")
+		}
+		m.scrollToCursor()
+	}
+	return m, nil
+}
+
+func (m *MessageLogPopupModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.scrollToCursor()
+}
+
+// adds an entry to the end of the log, moving the cursor to it
+func (m *MessageLogPopupModel) Add(entry LogEntry) {
+	m.entries = append(m.entries, entry)
+	m.cursor = len(m.entries) - 1
+	m.scrollToCursor()
+}
+
+func (m *MessageLogPopupModel) scrollToCursor() {
+	visible := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+}
+
+// the number of entries that fit, leaving space for the title, the selected entry and help
+func (m MessageLogPopupModel) listHeight() int {
+	return max(m.height-m.detailsHeight()-3, 1)
+}
+
+func (m MessageLogPopupModel) detailsHeight() int {
+	return max(m.height/3, 3)
+}
+
+func (m MessageLogPopupModel) View() string {
+	popupStyle := style.BasePanelStyle.
+		Width(m.width).
+		Height(m.height).
+		BorderForeground(colour.MessageLogPopupTitleBG)
+
+	title := style.Title(m.width-2, false).
+		Background(colour.MessageLogPopupTitleBG).
+		Foreground(colour.PanelTitleActiveFG).
+		MarginBottom(1).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("message log (%d)", len(m.entries)))
+
+	lineWidth := max(m.width-4, 1)
+	var lines []string
+	end := min(m.offset+m.listHeight(), len(m.entries))
+	for i := m.offset; i < end; i++ {
+		e := m.entries[i]
+		level := fmt.Sprintf("%-8s", e.Level.String())
+		text := strings.SplitN(e.Text, "\n", 2)[0]
+		var line string
+		if i == m.cursor {
+			line = fitText(fmt.Sprintf("%s %s %s", e.Time.Format(time.TimeOnly), level, text), lineWidth)
+			line = lipgloss.NewStyle().Background(colour.MessageLogSelectedBG).Foreground(colour.PanelTitleActiveFG).Width(lineWidth).Render(line)
+		} else {
+			level = lipgloss.NewStyle().Foreground(e.Level.colour()).Render(level)
+			line = fitText(fmt.Sprintf("%s %s %s", e.Time.Format(time.TimeOnly), level, text), lineWidth)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "nothing has been logged yet")
+	}
+	list := lipgloss.NewStyle().PaddingLeft(1).Height(m.listHeight()).Render(strings.Join(lines, "\n"))
+
+	// the whole of the selected entry, as the list only shows its first line
+	details := ""
+	if len(m.entries) > 0 {
+		e := m.entries[m.cursor]
+		details = wrap.String(e.Text, lineWidth)
+		if e.Code != "" {
+			details += "\n" + lipgloss.NewStyle().Foreground(colour.ListItemDescFG).Render(e.Code)
+		}
+	}
+	detailsView := lipgloss.NewStyle().
+		PaddingLeft(1).
+		Height(m.detailsHeight()).
+		MaxHeight(m.detailsHeight()).
+		Render(details)
+
+	help := lipgloss.NewStyle().
+		Foreground(colour.HelpKey).
+		PaddingLeft(1).
+		Render("↑/↓ move  y copy  esc close")
+
+	return popupStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, list, detailsView, help))
+}
@@ -0,0 +1,351 @@
+package component
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/keys"
+)
+
+// a match of the find pattern in the buffer, start and end count characters, submatches are the byte indexes
+// of the match and its groups (as returned by the regexp package) used to expand the replacement
+type findMatch struct {
+	start      int
+	end        int
+	submatches []int
+}
+
+// the find (and replace) bar of the query panel
+type queryFind struct {
+	active    bool
+	replacing bool
+	pattern   textinput.Model
+	replace   textinput.Model
+	// the replace input has focus, rather than the pattern
+	replaceFocused bool
+	regex          bool
+	matchCase      bool
+	// the pattern doesn't compile as a regex
+	err     error
+	matches []findMatch
+	current int
+	// where the cursor was when the bar was opened, typing finds the first match from here
+	origin int
+}
+
+func newQueryFind() queryFind {
+	pattern := textinput.New()
+	pattern.Prompt = "find: "
+	pattern.Placeholder = "text"
+	replace := textinput.New()
+	replace.Prompt = "replace: "
+	replace.Placeholder = "text"
+	return queryFind{pattern: pattern, replace: replace}
+}
+
+// opens the find bar from the cursor, with the replace input when replacing
+func (m *QueryPanelModel) openFind(buffer *queryBuffer, replacing bool) tea.Cmd {
+	f := &m.find
+	f.active = true
+	f.replacing = replacing
+	f.replaceFocused = false
+	_, f.origin = textOf(buffer.textarea)
+	f.replace.Blur()
+	f.pattern.CursorEnd()
+	m.findMatches(buffer)
+	return f.pattern.Focus()
+}
+
+// handles a key while the find bar is open, it takes every key
+func (m *QueryPanelModel) findKey(buffer *queryBuffer, msg tea.KeyMsg) tea.Cmd {
+	f := &m.find
+	switch {
+	case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup):
+		f.active = false
+		f.pattern.Blur()
+		f.replace.Blur()
+		if m.vim.enabled {
+			m.vimClampCursor(buffer)
+		}
+		return nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.SubmitForm):
+		if f.replaceFocused {
+			m.replaceMatch(buffer)
+		} else {
+			m.moveToMatch(buffer, f.current+1)
+		}
+		return nil
+
+	case msg.Type == tea.KeyDown:
+		m.moveToMatch(buffer, f.current+1)
+		return nil
+
+	case msg.Type == tea.KeyUp:
+		m.moveToMatch(buffer, f.current-1)
+		return nil
+
+	case msg.Type == tea.KeyTab || msg.Type == tea.KeyShiftTab:
+		if !f.replacing {
+			return nil
+		}
+		f.replaceFocused = !f.replaceFocused
+		if f.replaceFocused {
+			f.pattern.Blur()
+			return f.replace.Focus()
+		}
+		f.replace.Blur()
+		return f.pattern.Focus()
+
+	case key.Matches(msg, keys.DefaultKeyMap.Undo, keys.DefaultKeyMap.Redo):
+		// a replacement can be undone without closing the bar
+		_, cmd := m.panelKey(msg)
+		m.findMatches(buffer)
+		return cmd
+
+	case key.Matches(msg, keys.DefaultKeyMap.ReplaceAll):
+		if f.replacing {
+			m.replaceAll(buffer)
+		}
+		return nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.ToggleRegex):
+		f.regex = !f.regex
+		m.findMatches(buffer)
+		return nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.ToggleMatchCase):
+		f.matchCase = !f.matchCase
+		m.findMatches(buffer)
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if f.replaceFocused {
+		f.replace, cmd = f.replace.Update(msg)
+		return cmd
+	}
+	pattern := f.pattern.Value()
+	f.pattern, cmd = f.pattern.Update(msg)
+	if f.pattern.Value() != pattern {
+		m.findMatches(buffer)
+	}
+	return cmd
+}
+
+// the find pattern as a regex, nil when there's no pattern
+func (f queryFind) compile() (*regexp.Regexp, error) {
+	pattern := f.pattern.Value()
+	if pattern == "" {
+		return nil, nil
+	}
+	if !f.regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !f.matchCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// finds the matches in the buffer and moves the cursor to the first one from where the find started, or back to
+// where it started when nothing matches
+func (m *QueryPanelModel) findMatches(buffer *queryBuffer) {
+	f := &m.find
+	f.matches, f.current = nil, 0
+	re, err := f.compile()
+	f.err = err
+	if re == nil {
+		m.setCursor(buffer, f.origin)
+		return
+	}
+
+	value := buffer.textarea.Value()
+	offset, counted := 0, 0
+	for _, sub := range re.FindAllStringSubmatchIndex(value, -1) {
+		if sub[0] == sub[1] {
+			// an empty match, such as of a*, can't be highlighted or replaced usefully
+			continue
+		}
+		offset += utf8.RuneCountInString(value[counted:sub[0]])
+		start := offset
+		offset += utf8.RuneCountInString(value[sub[0]:sub[1]])
+		counted = sub[1]
+		f.matches = append(f.matches, findMatch{start: start, end: offset, submatches: sub})
+	}
+
+	if len(f.matches) == 0 {
+		m.setCursor(buffer, f.origin)
+		return
+	}
+	for i, match := range f.matches {
+		if match.start >= f.origin {
+			m.moveToMatch(buffer, i)
+			return
+		}
+	}
+	m.moveToMatch(buffer, 0)
+}
+
+// moves the cursor to the next (or previous) match of the last pattern, the matches are found again as the buffer
+// may have changed since the find bar was closed
+func (m *QueryPanelModel) findAgain(buffer *queryBuffer, forward bool) {
+	if m.find.pattern.Value() == "" {
+		return
+	}
+	_, offset := textOf(buffer.textarea)
+	m.find.origin = offset
+	if forward {
+		m.find.origin++
+	}
+	m.findMatches(buffer)
+	switch {
+	case len(m.find.matches) == 0:
+		m.setCursor(buffer, offset)
+	case !forward:
+		m.moveToMatch(buffer, m.find.current-1)
+	}
+}
+
+// moves the cursor to match i, wrapping around at either end
+func (m *QueryPanelModel) moveToMatch(buffer *queryBuffer, i int) {
+	f := &m.find
+	if len(f.matches) == 0 {
+		return
+	}
+	f.current = (i%len(f.matches) + len(f.matches)) % len(f.matches)
+	m.setCursor(buffer, f.matches[f.current].start)
+}
+
+// finds the matches again from the current one, as the buffer may have changed (e.g. been reloaded) since
+// they were found
+func (m *QueryPanelModel) refreshMatches(buffer *queryBuffer) {
+	if len(m.find.matches) > 0 {
+		m.find.origin = m.find.matches[m.find.current].start
+	}
+	m.findMatches(buffer)
+}
+
+// replaces the current match, and moves to the next one
+func (m *QueryPanelModel) replaceMatch(buffer *queryBuffer) {
+	f := &m.find
+	m.refreshMatches(buffer)
+	if len(f.matches) == 0 {
+		return
+	}
+	match := f.matches[f.current]
+	value := buffer.textarea.Value()
+	replacement := f.replacement(value, match)
+
+	buffer.pushUndo(buffer.state())
+	text := []rune(value)
+	text = insertText(removeText(text, match.start, match.end), match.start, replacement)
+	setText(&buffer.textarea, text, match.start)
+	// the next match is found from after the replacement, so it isn't matched again
+	f.origin = match.start + utf8.RuneCountInString(replacement)
+	m.findMatches(buffer)
+}
+
+// replaces every match, as one change
+func (m *QueryPanelModel) replaceAll(buffer *queryBuffer) {
+	f := &m.find
+	m.refreshMatches(buffer)
+	if len(f.matches) == 0 {
+		return
+	}
+	value := buffer.textarea.Value()
+	buffer.pushUndo(buffer.state())
+
+	var b []byte
+	last := 0
+	for _, match := range f.matches {
+		b = append(b, value[last:match.submatches[0]]...)
+		b = append(b, f.replacement(value, match)...)
+		last = match.submatches[1]
+	}
+	b = append(b, value[last:]...)
+
+	_, offset := textOf(buffer.textarea)
+	setText(&buffer.textarea, []rune(string(b)), offset)
+	f.origin = offset
+	m.findMatches(buffer)
+}
+
+// the text that replaces match, a regex replacement can refer to the groups of the match, such as $1
+func (f queryFind) replacement(value string, match findMatch) string {
+	if !f.regex {
+		return f.replace.Value()
+	}
+	re, err := f.compile()
+	if err != nil || re == nil {
+		return f.replace.Value()
+	}
+	return string(re.ExpandString(nil, f.replace.Value(), value, match.submatches))
+}
+
+// moves the cursor to offset in the buffer, scrolling it into view
+func (m *QueryPanelModel) setCursor(buffer *queryBuffer, offset int) {
+	text, _ := textOf(buffer.textarea)
+	setText(&buffer.textarea, text, offset)
+	buffer.textarea, _ = buffer.textarea.Update(nil)
+}
+
+// the matches drawn over the textarea, the current one stands out
+func (f queryFind) highlights() []textHighlight {
+	if !f.active {
+		return nil
+	}
+	matchStyle := lipgloss.NewStyle().Background(colour.FindMatchBG).Foreground(colour.FindMatchFG)
+	currentStyle := lipgloss.NewStyle().Background(colour.FindCurrentMatchBG).Foreground(colour.FindMatchFG)
+	var highlights []textHighlight
+	for i, match := range f.matches {
+		st := matchStyle
+		if i == f.current {
+			st = currentStyle
+		}
+		highlights = append(highlights, textHighlight{start: match.start, end: match.end, style: st})
+	}
+	return highlights
+}
+
+func (f *queryFind) setWidth(w int) {
+	// the inputs share what's left after their prompts, the options and the match count
+	f.pattern.Width = max((w-45)/2, 5)
+	f.replace.Width = max((w-45)/2, 5)
+}
+
+// the find bar, shown in place of the current statement
+func (f queryFind) View(width int) string {
+	option := func(name string, on bool) string {
+		st := lipgloss.NewStyle().Foreground(colour.ListItemDescFG)
+		if on {
+			st = lipgloss.NewStyle().Background(colour.FindOptionOnBG).Foreground(colour.FindMatchFG)
+		}
+		return st.Render(name)
+	}
+
+	var status string
+	switch {
+	case f.err != nil:
+		status = lipgloss.NewStyle().Foreground(colour.Error).Render("invalid regex")
+	case f.pattern.Value() == "":
+		status = ""
+	case len(f.matches) == 0:
+		status = lipgloss.NewStyle().Foreground(colour.Warning).Render("no matches")
+	default:
+		status = fmt.Sprintf("%d/%d", f.current+1, len(f.matches))
+	}
+
+	parts := []string{f.pattern.View()}
+	if f.replacing {
+		parts = append(parts, "  ", f.replace.View())
+	}
+	parts = append(parts, "  ", option(".*", f.regex), " ", option("Aa", f.matchCase), "  ", status)
+	return lipgloss.NewStyle().MarginLeft(1).MarginTop(1).MaxWidth(width).Render(lipgloss.JoinHorizontal(lipgloss.Top, parts...))
+}
@@ -2,30 +2,169 @@ package component
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/reflow/truncate"
 	"github.com/wheelibin/qrypad/internal/colour"
 	"github.com/wheelibin/qrypad/internal/commands"
 	"github.com/wheelibin/qrypad/internal/keys"
 	"github.com/wheelibin/qrypad/internal/style"
 )
 
+// a named query buffer, backed by a file in the database's query folder
+type queryBuffer struct {
+	name      string
+	filename  string
+	textarea  textarea.Model
+	lastSaved string
+	// the contents being saved, the watcher can see the file change before the save finishes
+	saving string
+	// the buffer before each change, and the changes undone
+	undo []bufferState
+	redo []bufferState
+	// the kind of the last edit typed, and where it left the cursor, so the next can be undone with it
+	lastEdit       editKind
+	lastEditCursor int
+}
+
+// edits typed one after another are undone together, while they are of the same kind
+type editKind int
+
+const (
+	editNone editKind = iota
+	// a character typed
+	editInsert
+	// a character deleted with backspace or delete
+	editDelete
+	// anything else, such as a paste or deleting to the end of the line, is undone on its own
+	editOther
+)
+
+// the text of a buffer and its cursor, as an offset into the text
+type bufferState struct {
+	value  string
+	cursor int
+}
+
+// the number of changes that can be undone
+const undoLimit = 500
+
+func (b queryBuffer) dirty() bool {
+	return b.textarea.Value() != b.lastSaved
+}
+
+// records the buffer as it was before a change
+func (b *queryBuffer) pushUndo(state bufferState) {
+	b.undo = append(b.undo, state)
+	if len(b.undo) > undoLimit {
+		b.undo = b.undo[len(b.undo)-undoLimit:]
+	}
+	b.redo = nil
+	b.lastEdit = editNone
+}
+
+// records a key typed into the textarea, which changed the buffer from before, starting a new undo step unless it
+// carries on the last edit: typing a word, or deleting characters one after another
+func (b *queryBuffer) recordEdit(before bufferState, msg tea.KeyMsg) {
+	after := b.state()
+	if after.value == before.value {
+		if after.cursor != before.cursor {
+			// moving the cursor ends the edit
+			b.lastEdit = editNone
+		}
+		return
+	}
+
+	kind := editOther
+	text := []rune(after.value)
+	switch n := len(text) - utf8.RuneCountInString(before.value); {
+	case n == 1 && (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && !msg.Paste:
+		kind = editInsert
+	case n == -1 && (msg.Type == tea.KeyBackspace || msg.Type == tea.KeyDelete || msg.Type == tea.KeyCtrlD):
+		kind = editDelete
+	}
+	// a word typed after a space starts a new step
+	wordStart := kind == editInsert && after.cursor >= 2 && !unicode.IsSpace(text[after.cursor-1]) && unicode.IsSpace(text[after.cursor-2])
+	if kind == editOther || kind != b.lastEdit || before.cursor != b.lastEditCursor || wordStart {
+		b.pushUndo(before)
+	}
+	b.lastEdit, b.lastEditCursor = kind, after.cursor
+}
+
+// puts the buffer back as it was before the last change, false when there's nothing to undo
+func (b *queryBuffer) undoChange() bool {
+	if len(b.undo) == 0 {
+		return false
+	}
+	b.redo = append(b.redo, b.state())
+	b.restore(b.undo[len(b.undo)-1])
+	b.undo = b.undo[:len(b.undo)-1]
+	b.lastEdit = editNone
+	return true
+}
+
+// makes the last change undone again, false when there's nothing to redo
+func (b *queryBuffer) redoChange() bool {
+	if len(b.redo) == 0 {
+		return false
+	}
+	b.undo = append(b.undo, b.state())
+	b.restore(b.redo[len(b.redo)-1])
+	b.redo = b.redo[:len(b.redo)-1]
+	b.lastEdit = editNone
+	return true
+}
+
+func (b queryBuffer) state() bufferState {
+	text, offset := textOf(b.textarea)
+	return bufferState{value: string(text), cursor: offset}
+}
+
+func (b *queryBuffer) restore(state bufferState) {
+	setText(&b.textarea, []rune(state.value), state.cursor)
+	b.textarea, _ = b.textarea.Update(nil)
+}
+
 type QueryPanelModel struct {
 	active           bool
 	width            int
 	height           int
-	queryBuffer      textarea.Model
+	buffers          []queryBuffer
+	activeBuffer     int
 	dbAlias          string
 	CurrentStatement string
-	dirty            bool
-	filename         string
+	// the cursor line is highlighted after moving to an error, until the next key
+	errorHighlighted bool
+	vim              vimEditor
+	find             queryFind
+	// whether the last key was used by the panel, by a vim command or the find bar, and shouldn't be handled by
+	// the ui as well
+	tookKey bool
+}
+
+// where an error is in the query buffer, the column and length of the failing token count characters
+type ErrorPosition struct {
+	Line       string
+	LineNumber int
+	Column     int
+	Length     int
 }
 
 func NewQueryPanelModel(dbAlias string) QueryPanelModel {
+	return QueryPanelModel{dbAlias: dbAlias, find: newQueryFind()}
+}
+
+func newQueryTextarea() textarea.Model {
 	ta := textarea.New()
 	ta.Placeholder = "sql statement(s)..."
 	ta.Prompt = "┃ "
@@ -36,11 +175,11 @@ func NewQueryPanelModel(dbAlias string) QueryPanelModel {
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	ta.ShowLineNumbers = false
 
-	return QueryPanelModel{dbAlias: dbAlias, queryBuffer: ta}
+	return ta
 }
 
 func (m QueryPanelModel) Init() tea.Cmd {
-	return tea.Batch(commands.ReadOrCreateQueryFile(m.dbAlias))
+	return tea.Batch(commands.ReadQueryFiles(m.dbAlias))
 }
 
 func (m QueryPanelModel) Update(msg tea.Msg) (QueryPanelModel, tea.Cmd) {
@@ -52,52 +191,491 @@ func (m QueryPanelModel) Update(msg tea.Msg) (QueryPanelModel, tea.Cmd) {
 
 	switch msg := msg.(type) {
 
+	case commands.QueryFilesReadMsg:
+		m.buffers = nil
+		m.activeBuffer = 0
+		for _, f := range msg.Files {
+			m.addBuffer(f.FileName, f.Contents)
+		}
+
 	case commands.QueryFileReadMsg:
-		m.filename = msg.FileName
-		m.queryBuffer.SetValue(string(msg.Contents))
+		if i := m.bufferIndex(msg.FileName); i >= 0 {
+			if state := m.buffers[i].state(); state.value != msg.Contents {
+				// reloading the file can be undone
+				m.buffers[i].pushUndo(state)
+			}
+			m.buffers[i].textarea.SetValue(msg.Contents)
+			m.buffers[i].lastSaved = msg.Contents
+			if i == m.activeBuffer && m.find.active {
+				// the matches were of the old contents
+				m.refreshMatches(&m.buffers[i])
+			}
+		} else {
+			// a new buffer
+			m.addBuffer(msg.FileName, msg.Contents)
+			m.setActiveBuffer(len(m.buffers) - 1)
+		}
+
+	case commands.QueryFileSavedMsg:
+		if i := m.bufferIndex(msg.FileName); i >= 0 {
+			m.buffers[i].lastSaved = msg.Contents
+			if m.buffers[i].saving == msg.Contents {
+				m.buffers[i].saving = ""
+			}
+		}
+
+	case commands.QueryFileRenamedMsg:
+		if i := m.bufferIndex(msg.OldFileName); i >= 0 {
+			m.buffers[i].filename = msg.FileName
+			m.buffers[i].name = bufferName(msg.FileName)
+		}
+
+	case commands.QueryFileDeletedMsg:
+		if i := m.bufferIndex(msg.FileName); i >= 0 {
+			if i == m.activeBuffer {
+				// an insert into the removed buffer can't be undone
+				m.vim.mode = vimNormal
+			}
+			m.buffers = append(m.buffers[:i], m.buffers[i+1:]...)
+			if len(m.buffers) == 0 {
+				// the last buffer's file is gone, reading the folder again creates the default buffer
+				m.activeBuffer = 0
+				m.CurrentStatement = ""
+				cmds = append(cmds, commands.ReadQueryFiles(m.dbAlias))
+			} else {
+				m.setActiveBuffer(min(m.activeBuffer, len(m.buffers)-1))
+			}
+		}
 
 	case commands.EditorFinishedMsg:
-		cmds = append(cmds, commands.ReadOrCreateQueryFile(m.dbAlias))
+		cmds = append(cmds, commands.ReadQueryFile(m.GetFilename()))
+
+	case commands.CommandKeyMsg:
+		// only the panel's commands are handled here, the key isn't typed into the buffer
+		m.tookKey = false
+		if m.active && len(m.buffers) > 0 {
+			_, cmd = m.panelKey(tea.KeyMsg(msg))
+			cmds = append(cmds, cmd)
+			m.CurrentStatement = m.GetCurrentStatement()
+		}
+		return m, tea.Batch(cmds...)
+
+	case tea.KeyMsg:
+		m.tookKey = false
+		if m.errorHighlighted {
+			m.errorHighlighted = false
+			for i := range m.buffers {
+				m.buffers[i].textarea.FocusedStyle.CursorLine = lipgloss.NewStyle()
+			}
+		}
+		if m.active && len(m.buffers) > 0 && m.find.active && !key.Matches(msg, keys.DefaultKeyMap.Quit) {
+			// the find bar takes every key while it's open
+			m.tookKey = true
+			cmds = append(cmds, m.findKey(&m.buffers[m.activeBuffer], msg))
+			m.CurrentStatement = m.GetCurrentStatement()
+			return m, tea.Batch(cmds...)
+		}
+		if m.active && len(m.buffers) > 0 {
+			if handled, cmd := m.panelKey(msg); handled {
+				m.CurrentStatement = m.GetCurrentStatement()
+				return m, tea.Batch(append(cmds, cmd)...)
+			}
+		}
+	}
+
+	if len(m.buffers) == 0 {
+		return m, tea.Batch(cmds...)
 	}
 
 	// update components
+	buffer := &m.buffers[m.activeBuffer]
 	if m.active {
-		if !m.queryBuffer.Focused() {
-			cmds = append(cmds, m.queryBuffer.Focus())
+		if !buffer.textarea.Focused() {
+			cmds = append(cmds, buffer.textarea.Focus())
+		}
+		keyMsg, isKey := msg.(tea.KeyMsg)
+		switch {
+		case isKey && m.vim.enabled:
+			cmd = m.vimKey(buffer, keyMsg)
+		case isKey:
+			before := buffer.state()
+			buffer.textarea, cmd = buffer.textarea.Update(msg)
+			buffer.recordEdit(before, keyMsg)
+		default:
+			buffer.textarea, cmd = buffer.textarea.Update(msg)
 		}
-		m.queryBuffer, cmd = m.queryBuffer.Update(msg)
 		cmds = append(cmds, cmd)
+		if m.find.active && !isKey {
+			// the find bar's cursors blink
+			m.find.pattern, cmd = m.find.pattern.Update(msg)
+			cmds = append(cmds, cmd)
+			m.find.replace, cmd = m.find.replace.Update(msg)
+			cmds = append(cmds, cmd)
+		}
 
-		m.CurrentStatement = getStatementAtCursor(m.queryBuffer.Value(), m.queryBuffer.Line())
+		m.CurrentStatement = m.GetCurrentStatement()
 
 	} else {
-		m.queryBuffer.Blur()
+		buffer.textarea.Blur()
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// handles the panel's own commands, such as switching buffers and undo, and the keys the ui handles for buffers,
+// handled is true when the key was one of them
+func (m *QueryPanelModel) panelKey(msg tea.KeyMsg) (handled bool, cmd tea.Cmd) {
+	buffer := &m.buffers[m.activeBuffer]
+	switch {
+	case key.Matches(msg, keys.DefaultKeyMap.NextBuffer):
+		m.setActiveBuffer((m.activeBuffer + 1) % len(m.buffers))
+		return true, nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.PrevBuffer):
+		i := m.activeBuffer - 1
+		if i < 0 {
+			i = len(m.buffers) - 1
+		}
+		m.setActiveBuffer(i)
+		return true, nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.Undo, keys.DefaultKeyMap.Redo):
+		if m.vim.mode == vimInsert {
+			// what was typed so far is undone on its own
+			m.vimBreakInsert(buffer)
+		}
+		if key.Matches(msg, keys.DefaultKeyMap.Undo) {
+			buffer.undoChange()
+		} else {
+			buffer.redoChange()
+		}
+		if m.vim.enabled {
+			m.vim.insertStart = buffer.state()
+			if m.vim.mode != vimInsert {
+				m.vim.mode = vimNormal
+				m.vimClampCursor(buffer)
+			}
+		}
+		return true, nil
+
+	case key.Matches(msg, keys.DefaultKeyMap.Find, keys.DefaultKeyMap.Replace):
+		if m.vim.mode == vimVisual || m.vim.mode == vimVisualLine {
+			m.vim.mode = vimNormal
+		}
+		return true, m.openFind(buffer, key.Matches(msg, keys.DefaultKeyMap.Replace))
+
+	case key.Matches(msg, keys.DefaultKeyMap.NewBuffer, keys.DefaultKeyMap.RenameBuffer, keys.DefaultKeyMap.CloseBuffer, keys.DefaultKeyMap.CommandPalette):
+		// handled by the ui, don't let the textarea insert the key
+		return true, nil
+	}
+	return false, nil
+}
+
+// sets how the buffers are edited, "vim" for vim's modal editing, anything else edits them as plain text
+func (m *QueryPanelModel) SetEditorMode(mode string) {
+	m.vim = vimEditor{enabled: mode == EditorModeVim}
+}
+
+// whether the last key was used by a vim command or the find bar, and shouldn't be handled by the ui as well
+func (m QueryPanelModel) TookKey() bool {
+	return m.tookKey
+}
+
+func (m *QueryPanelModel) addBuffer(filename string, contents string) {
+	ta := newQueryTextarea()
+	ta.SetValue(contents)
+	m.buffers = append(m.buffers, queryBuffer{
+		name:      bufferName(filename),
+		filename:  filename,
+		textarea:  ta,
+		lastSaved: contents,
+	})
+	m.SetSize(m.width, m.height)
+}
+
+func (m *QueryPanelModel) setActiveBuffer(index int) {
+	if index < 0 || index >= len(m.buffers) {
+		return
+	}
+	if m.activeBuffer < len(m.buffers) {
+		// the active buffer may be the one that was just removed
+		if m.vim.mode == vimInsert {
+			m.leaveInsert(&m.buffers[m.activeBuffer])
+		}
+		m.buffers[m.activeBuffer].textarea.Blur()
+	}
+	// a selection, half typed command or find doesn't carry over to the other buffer
+	m.vim.mode = vimNormal
+	m.vim.typed = nil
+	m.find.active = false
+	m.activeBuffer = index
+}
+
+func (m QueryPanelModel) bufferIndex(filename string) int {
+	for i, b := range m.buffers {
+		if b.filename == filename {
+			return i
+		}
+	}
+	return -1
+}
+
+func bufferName(filename string) string {
+	return strings.TrimSuffix(filepath.Base(filename), ".sql")
+}
+
 func (m QueryPanelModel) GetCurrentStatement() string {
-	return getStatementAtCursor(m.queryBuffer.Value(), m.queryBuffer.Line())
+	if len(m.buffers) == 0 {
+		return ""
+	}
+	ta := m.buffers[m.activeBuffer].textarea
+	if m.vim.mode == vimVisual || m.vim.mode == vimVisualLine {
+		// the visual selection is run rather than the statement
+		text, offset := textOf(ta)
+		start, end := m.vim.selection(text, offset)
+		return strings.TrimSpace(string(text[start:end]))
+	}
+	return getStatementAtCursor(ta.Value(), ta.Line())
+}
+
+// moves the cursor to the character at offset in statement, which was run from the current buffer, and
+// highlights its line, ok is false when the line can't be found in the buffer
+func (m *QueryPanelModel) MoveToError(statement string, offset int) (position ErrorPosition, ok bool) {
+	if len(m.buffers) == 0 {
+		return ErrorPosition{}, false
+	}
+
+	runes := []rune(statement)
+	before := string(runes[:min(max(offset, 0), len(runes))])
+	statementLines := strings.Split(statement, "\n")
+	lineIndex := strings.Count(before, "\n")
+	column := len([]rune(before[strings.LastIndex(before, "\n")+1:]))
+	if strings.TrimSpace(statementLines[lineIndex]) == "" && lineIndex > 0 {
+		// the error is at the end of the statement, after its last line
+		lineIndex--
+		column = len([]rune(statementLines[lineIndex]))
+	}
+	line := statementLines[lineIndex]
+
+	// the statement's lines are taken from the buffer, so the matching line nearest the cursor is the one that was run
+	ta := &m.buffers[m.activeBuffer].textarea
+	bufferLine := -1
+	for i, l := range strings.Split(ta.Value(), "\n") {
+		if l == line && (bufferLine < 0 || abs(i-ta.Line()) < abs(bufferLine-ta.Line())) {
+			bufferLine = i
+		}
+	}
+	if bufferLine < 0 {
+		return ErrorPosition{}, false
+	}
+
+	for ta.Line() < bufferLine {
+		ta.CursorDown()
+	}
+	for ta.Line() > bufferLine {
+		ta.CursorUp()
+	}
+	ta.SetCursor(column)
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle().Background(colour.ErrorLineBG).Foreground(colour.Error)
+	m.errorHighlighted = true
+
+	return ErrorPosition{Line: line, LineNumber: bufferLine + 1, Column: column, Length: tokenLength(line, column)}, true
+}
+
+// the query text starts below the panel's border, title and its margin, and after the border and prompt
+const (
+	queryTextX = 3
+	queryTextY = 3
+)
+
+// a row of the query text as it is shown (soft-wrapped), the line it is part of and the column it starts at
+type visualRow struct {
+	line  int
+	start int
+}
+
+// the rows the text is shown on, found by moving a copy of the textarea's cursor down through them
+func visualRows(ta textarea.Model) []visualRow {
+	for ta.Line() > 0 {
+		ta.CursorUp()
+	}
+	ta.SetCursor(0)
+
+	var rows []visualRow
+	for {
+		row := visualRow{line: ta.Line(), start: ta.LineInfo().StartColumn}
+		rows = append(rows, row)
+		ta.CursorDown()
+		if ta.Line() == row.line && ta.LineInfo().StartColumn == row.start {
+			return rows
+		}
+	}
+}
+
+// the first row shown, which the textarea doesn't expose, found by matching what it shows against the rows,
+// trying those that would keep the cursor in view first
+func scrollOffset(ta textarea.Model, rows []visualRow, lines [][]rune) int {
+	rowText := func(i int) string {
+		end := len(lines[rows[i].line])
+		if i+1 < len(rows) && rows[i+1].line == rows[i].line {
+			end = rows[i+1].start
+		}
+		return strings.TrimSpace(string(lines[rows[i].line][rows[i].start:end]))
+	}
+
+	cursor := 0
+	for i, r := range rows {
+		if r.line == ta.Line() && r.start == ta.LineInfo().StartColumn {
+			cursor = i
+		}
+	}
+	shown := strings.Split(ansi.Strip(ta.View()), "\n")
+	matches := func(first int) bool {
+		for i := 0; i < len(shown) && first+i < len(rows); i++ {
+			if strings.TrimSpace(strings.TrimPrefix(shown[i], ta.Prompt)) != rowText(first+i) {
+				return false
+			}
+		}
+		return true
+	}
+	nearest := max(cursor-len(shown)+1, 0)
+	for first := nearest; first <= cursor; first++ {
+		if matches(first) {
+			return first
+		}
+	}
+	for first := range rows {
+		if matches(first) {
+			return first
+		}
+	}
+	return nearest
+}
+
+// moves the cursor to the character shown at x, y (from the top left of the panel)
+func (m *QueryPanelModel) MoveCursorTo(x, y int) {
+	if len(m.buffers) == 0 || y < queryTextY {
+		return
+	}
+	ta := &m.buffers[m.activeBuffer].textarea
+	var lines [][]rune
+	for _, l := range strings.Split(ta.Value(), "\n") {
+		lines = append(lines, []rune(l))
+	}
+	rows := visualRows(*ta)
+	i := min(scrollOffset(*ta, rows, lines)+y-queryTextY, len(rows)-1)
+	row := rows[i]
+
+	for ta.Line() < row.line {
+		ta.CursorDown()
+	}
+	for ta.Line() > row.line {
+		ta.CursorUp()
+	}
+
+	// x counts cells on the screen, so wide characters take two
+	end := len(lines[row.line])
+	if i+1 < len(rows) && rows[i+1].line == row.line {
+		// the last column of a wrapped row is the start of the next one
+		end = rows[i+1].start - 1
+	}
+	col, width := row.start, 0
+	for col < end && width+runewidth.RuneWidth(lines[row.line][col]) <= x-queryTextX {
+		width += runewidth.RuneWidth(lines[row.line][col])
+		col++
+	}
+	ta.SetCursor(col)
+}
+
+// moves the cursor up (n < 0) or down by n rows, which scrolls the text when it reaches the top or bottom
+func (m *QueryPanelModel) ScrollBy(n int) {
+	if len(m.buffers) == 0 {
+		return
+	}
+	ta := &m.buffers[m.activeBuffer].textarea
+	for ; n < 0; n++ {
+		ta.CursorUp()
+	}
+	for ; n > 0; n-- {
+		ta.CursorDown()
+	}
 }
 
 func (m QueryPanelModel) GetValue() string {
-	return m.queryBuffer.Value()
+	if len(m.buffers) == 0 {
+		return ""
+	}
+	return m.buffers[m.activeBuffer].textarea.Value()
 }
 
 func (m QueryPanelModel) GetFilename() string {
-	return m.filename
+	if len(m.buffers) == 0 {
+		return ""
+	}
+	return m.buffers[m.activeBuffer].filename
+}
+
+func (m QueryPanelModel) GetBufferName() string {
+	if len(m.buffers) == 0 {
+		return ""
+	}
+	return m.buffers[m.activeBuffer].name
 }
 
-func (m *QueryPanelModel) SetDirty(dirty bool) {
-	m.dirty = dirty
+// returns the filename and contents of every buffer with unsaved changes
+func (m QueryPanelModel) GetDirtyBuffers() map[string]string {
+	dirty := map[string]string{}
+	for _, b := range m.buffers {
+		if b.dirty() {
+			dirty[b.filename] = b.textarea.Value()
+		}
+	}
+	return dirty
+}
+
+// reports whether the file contents on disk differ from both the buffer and what was last saved/loaded
+func (m QueryPanelModel) IsChangedOnDisk(filename string, contents string) bool {
+	i := m.bufferIndex(filename)
+	if i < 0 {
+		return false
+	}
+	b := m.buffers[i]
+	return contents != b.lastSaved && contents != b.saving && contents != b.textarea.Value()
+}
+
+// records that the buffer's file is being saved with contents, so the change isn't taken for one made elsewhere
+func (m *QueryPanelModel) MarkSaving(filename string, contents string) {
+	if i := m.bufferIndex(filename); i >= 0 {
+		m.buffers[i].saving = contents
+	}
+}
+
+func (m QueryPanelModel) IsDirty(filename string) bool {
+	i := m.bufferIndex(filename)
+	return i >= 0 && m.buffers[i].dirty()
+}
+
+// whether closing the active buffer loses nothing, it's saved empty and has no changes to undo
+func (m QueryPanelModel) IsBlank() bool {
+	if len(m.buffers) == 0 {
+		return true
+	}
+	b := m.buffers[m.activeBuffer]
+	return b.lastSaved == "" && !b.dirty() && len(b.undo) == 0 && len(b.redo) == 0
+}
+
+func (m QueryPanelModel) GetBufferCount() int {
+	return len(m.buffers)
 }
 
 func (m *QueryPanelModel) SetSize(w, h int) {
 	m.width = w
 	m.height = h
-	m.queryBuffer.SetWidth(m.width)
-	m.queryBuffer.SetHeight(m.height - style.CurrentStatementHeight - style.TitleHeight - style.Margin - 1)
+	for i := range m.buffers {
+		m.buffers[i].textarea.SetWidth(m.width)
+		m.buffers[i].textarea.SetHeight(m.height - style.CurrentStatementHeight - style.TitleHeight - style.Margin - 1)
+	}
+	m.find.setWidth(m.width)
 }
 
 func (m *QueryPanelModel) SetActive(active bool) {
@@ -131,13 +709,41 @@ func (m QueryPanelModel) View() string {
 		}
 		currentStatement = currentStatementStyle.Render(fmt.Sprintf("(%s) execute: %s", keys.DefaultKeyMap.ExecuteQuery.Keys()[0], strings.ReplaceAll(truncated, "\n", " ")))
 	}
+	if m.find.active && m.active {
+		currentStatement = m.find.View(m.width - 2)
+	}
+
+	titleStyle := style.Title(m.width-2, m.active)
+	tabTextStyle := lipgloss.NewStyle().Background(titleStyle.GetBackground())
+
+	// one tab per buffer, the active one in brackets and [+] marking unsaved changes
+	var tabs []string
+	for i, b := range m.buffers {
+		text := b.name
+		if b.dirty() {
+			text = text + " [+]"
+		}
+		if i == m.activeBuffer {
+			text = "[" + text + "]"
+		} else {
+			text = " " + text + " "
+		}
+		tabs = append(tabs, text)
+	}
+	name := "queries"
+	if m.vim.enabled {
+		name += " " + m.vim.mode.String()
+	}
+	tw := max(lipgloss.Width(titleStyle.Render(""))-3-len(name), 0)
+	tabText := truncate.StringWithTail(strings.Join(tabs, " "), uint(tw), "…")
+	title := titleStyle.MarginBottom(1).Render(name + lipgloss.PlaceHorizontal(tw, lipgloss.Right, tabTextStyle.Render(tabText)))
 
-	text := "queries"
-	if m.dirty {
-		text = text + " [+]"
+	buffer := ""
+	if len(m.buffers) > 0 {
+		ta := m.buffers[m.activeBuffer].textarea
+		buffer = highlightTextarea(ta, append(m.find.highlights(), m.vim.selectionHighlights(ta)...))
 	}
-	title := style.Title(m.width-2, m.active).MarginBottom(1).Render(text)
 
-	v := lipgloss.JoinVertical(lipgloss.Left, title, m.queryBuffer.View(), currentStatement)
+	v := lipgloss.JoinVertical(lipgloss.Left, title, buffer, currentStatement)
 	return panelStyle.Render(v)
 }
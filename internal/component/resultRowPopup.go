@@ -1,12 +1,17 @@
 package component
 
 import (
+	"fmt"
 	"math"
+	"slices"
+	"sort"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
 	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/keys"
 	"github.com/wheelibin/qrypad/internal/style"
 )
 
@@ -14,6 +19,13 @@ type ResultRowPopupModel struct {
 	width  int
 	height int
 	table  table.Model
+	// the fields in the order they are shown (unless sorted), and the record's values
+	columns      []string
+	data         map[string]any
+	alphabetical bool
+	// the record's position in the results, total is 0 when it isn't known
+	index int
+	total int
 }
 
 func NewResultRowPopupModel() ResultRowPopupModel {
@@ -42,30 +54,64 @@ func (m ResultRowPopupModel) Update(msg tea.Msg) (ResultRowPopupModel, tea.Cmd)
 		cmds []tea.Cmd
 	)
 
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if !m.IsFiltering() && key.Matches(msg, keys.DefaultKeyMap.SortFields) {
+			m.alphabetical = !m.alphabetical
+			m.setRows()
+			return m, nil
+		}
+	}
+
 	m.table, cmd = m.table.Update(msg)
 	cmds = append(cmds, cmd)
 
 	return m, tea.Batch(cmds...)
 }
 
-func (m *ResultRowPopupModel) SetData(data map[string]any) {
+// shows the record, with the fields in the order of columns (or alphabetical when columns is nil)
+func (m *ResultRowPopupModel) SetData(columns []string, data map[string]any) {
 	if data == nil {
 		return
 	}
 
+	if columns == nil {
+		for k := range data {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+	}
+	m.columns = columns
+	m.data = data
+	m.index = 0
+	m.total = 0
+
 	cols := []table.Column{
 		table.NewFlexColumn("field", "field", 1).WithFiltered(true),
 		table.NewFlexColumn("value", "value", 3).WithFiltered(true),
 	}
-	rows := []table.Row{}
+	m.table = m.table.WithColumns(cols)
+	m.setRows()
+}
+
+// sets the position of the record in the results, shown as "record n of total"
+func (m *ResultRowPopupModel) SetPosition(index int, total int) {
+	m.index = index
+	m.total = total
+}
 
-	for k, v := range data {
-		rows = append(rows, table.Row{Data: map[string]any{"field": k, "value": v}})
+func (m *ResultRowPopupModel) setRows() {
+	fields := m.columns
+	if m.alphabetical {
+		fields = slices.Clone(fields)
+		sort.Strings(fields)
 	}
 
+	rows := []table.Row{}
+	for _, f := range fields {
+		rows = append(rows, table.Row{Data: map[string]any{"field": f, "value": m.data[f]}})
+	}
 	m.table = m.table.WithRows(rows)
-	m.table = m.table.WithColumns(cols)
-	m.table = m.table.SortByAsc("field")
 }
 
 func (m *ResultRowPopupModel) SetSize(w, h int) {
@@ -77,6 +123,22 @@ func (m *ResultRowPopupModel) SetSize(w, h int) {
 	m.table = m.table.WithTargetWidth(w)
 }
 
+// the name of the highlighted field
+func (m ResultRowPopupModel) GetSelectedField() string {
+	field, _ := m.table.HighlightedRow().Data["field"].(string)
+	return field
+}
+
+// the value of the highlighted field
+func (m ResultRowPopupModel) GetSelectedValue() any {
+	return m.table.HighlightedRow().Data["value"]
+}
+
+// reports whether the filter input has focus, so keys are being typed into it
+func (m ResultRowPopupModel) IsFiltering() bool {
+	return m.table.GetIsFilterInputFocused()
+}
+
 func (m ResultRowPopupModel) View() string {
 	var panelStyle = style.BasePanelStyle
 	panelStyle = panelStyle.Width(m.width)
@@ -86,13 +148,21 @@ func (m ResultRowPopupModel) View() string {
 
 	content := lipgloss.JoinVertical(lipgloss.Left, m.table.View())
 
-	title := style.Title(m.width-2, false).
+	title := "record details"
+	if m.total > 0 {
+		title = fmt.Sprintf("record %d of %d", m.index+1, m.total)
+	}
+	if m.alphabetical {
+		title += " (fields sorted)"
+	}
+
+	titleBar := style.Title(m.width-2, false).
 		Background(colour.ResultRowPopupTitleBG).
 		Foreground(colour.PanelTitleActiveFG).
 		Align(lipgloss.Center).
-		Render("record details")
+		Render(title)
 
-	v := lipgloss.JoinVertical(lipgloss.Left, title, content)
+	v := lipgloss.JoinVertical(lipgloss.Left, titleBar, content)
 	return panelStyle.Render(v)
 
 }
@@ -1,16 +1,23 @@
 package component
 
 import (
+	"cmp"
 	"fmt"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/evertras/bubble-table/table"
+	"github.com/muesli/reflow/truncate"
 	"github.com/wheelibin/qrypad/internal/colour"
 	"github.com/wheelibin/qrypad/internal/commands"
 	"github.com/wheelibin/qrypad/internal/db"
+	"github.com/wheelibin/qrypad/internal/keys"
 	"github.com/wheelibin/qrypad/internal/style"
 )
 
@@ -21,6 +28,17 @@ type ResultsPanelModel struct {
 	loading bool
 	spinner spinner.Model
 	table   table.Model
+	data    *db.Data
+	// the order the columns are shown in, and those that are hidden
+	columns []string
+	hidden  map[string]bool
+	frozen  int
+	// the selected (visible) column, and the widths the visible columns are shown at
+	selectedColumn int
+	columnWidths   []int
+	// the column the rows are sorted by in the panel (rather than by the database)
+	sortColumn string
+	sortDesc   bool
 }
 
 func NewResultsPanelModel() ResultsPanelModel {
@@ -32,15 +50,17 @@ func NewResultsPanelModel() ResultsPanelModel {
 				Align(lipgloss.Left),
 		).
 		HeaderStyle(style.TableHeaderStyle).
-		WithHorizontalFreezeColumnCount(1).
+		WithHorizontalFreezeColumnCount(defaultFrozenColumns).
 		Filtered(true)
 
 	s := spinner.New()
 	s.Spinner = spinner.Points
 	s.Style = lipgloss.NewStyle().Foreground(colour.Spinner)
-	return ResultsPanelModel{table: t, spinner: s}
+	return ResultsPanelModel{table: t, spinner: s, hidden: map[string]bool{}, frozen: defaultFrozenColumns}
 }
 
+const defaultFrozenColumns = 1
+
 func (m ResultsPanelModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
@@ -55,6 +75,45 @@ func (m ResultsPanelModel) Update(msg tea.Msg) (ResultsPanelModel, tea.Cmd) {
 	)
 
 	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.active && m.data != nil && !m.IsFiltering() {
+			switch {
+			case key.Matches(msg, keys.DefaultKeyMap.NextColumn):
+				m.selectColumn(m.selectedColumn + 1)
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.PrevColumn):
+				m.selectColumn(m.selectedColumn - 1)
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.SortColumn):
+				// table data is sorted by the database instead
+				if m.GetTableQuery() == nil {
+					m.toggleSort()
+				}
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.HideColumn):
+				m.hideSelectedColumn()
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.ShowColumns):
+				m.hidden = map[string]bool{}
+				m.refreshColumns()
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.MoveColumnLeft):
+				m.moveSelectedColumn(-1)
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.MoveColumnRight):
+				m.moveSelectedColumn(1)
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.FreezeMoreColumns):
+				m.frozen = min(m.frozen+1, max(len(m.visibleColumns())-1, 0))
+				m.refreshColumns()
+				return m, nil
+			case key.Matches(msg, keys.DefaultKeyMap.FreezeFewerColumns):
+				m.frozen = max(m.frozen-1, 0)
+				m.refreshColumns()
+				return m, nil
+			}
+		}
+
 	case spinner.TickMsg:
 		if m.loading {
 			m.spinner, cmd = m.spinner.Update(msg)
@@ -81,24 +140,185 @@ func (m *ResultsPanelModel) SetData(data *db.Data) {
 		return
 	}
 
-	cols := []table.Column{}
-	rows := []table.Row{}
+	// keep the column layout and sorting when paging or sorting the same columns
+	if m.data == nil || !slices.Equal(m.data.Columns, data.Columns) {
+		m.columns = slices.Clone(data.Columns)
+		m.hidden = map[string]bool{}
+		m.frozen = defaultFrozenColumns
+		m.selectedColumn = 0
+		m.sortColumn = ""
+	}
+	m.data = data
+	m.setRows()
+	m.setColumns()
 
-	// get cols
-	for _, c := range data.Columns {
-		w := getColumnWidth(c, *data)
-		cols = append(cols, table.NewColumn(c, c, w).WithFiltered(true))
+	m.loading = false
+	m.SetSize(m.width, m.height)
+	m.scrollToSelectedColumn()
+}
+
+// sets the table rows, in the order of the panel's sort
+func (m *ResultsPanelModel) setRows() {
+	data := slices.Clone(m.data.Rows)
+	if m.sortColumn != "" {
+		slices.SortStableFunc(data, func(a, b map[string]any) int {
+			c := compareValues(a[m.sortColumn], b[m.sortColumn])
+			if m.sortDesc {
+				return -c
+			}
+			return c
+		})
 	}
-	for _, row := range data.Rows {
+
+	rows := []table.Row{}
+	for _, row := range data {
 		rows = append(rows, table.Row{Data: row})
 	}
+	m.table = m.table.WithRows(rows)
+}
+
+// compares values numerically when they are both numbers, NULLs sort last
+func compareValues(a, b any) int {
+	as, bs := fmt.Sprintf("%v", a), fmt.Sprintf("%v", b)
+	switch {
+	case as == bs:
+		return 0
+	case as == "NULL":
+		return 1
+	case bs == "NULL":
+		return -1
+	}
+	af, aErr := strconv.ParseFloat(as, 64)
+	bf, bErr := strconv.ParseFloat(bs, 64)
+	if aErr == nil && bErr == nil {
+		return cmp.Compare(af, bf)
+	}
+	return strings.Compare(as, bs)
+}
 
+// cycles the panel's sort of the selected column through ascending, descending, then unsorted
+func (m *ResultsPanelModel) toggleSort() {
+	col := m.GetSelectedColumn()
+	switch {
+	case col == "":
+		return
+	case m.sortColumn != col:
+		m.sortColumn, m.sortDesc = col, false
+	case !m.sortDesc:
+		m.sortDesc = true
+	default:
+		m.sortColumn, m.sortDesc = "", false
+	}
+	m.setRows()
+	m.setColumns()
+}
+
+func (m *ResultsPanelModel) hideSelectedColumn() {
+	visible := m.visibleColumns()
+	// keep at least one column
+	if len(visible) <= 1 {
+		return
+	}
+	m.hidden[visible[m.selectedColumn]] = true
+	m.refreshColumns()
+}
+
+// moves the selected column one place left (-1) or right (1), past any hidden columns
+func (m *ResultsPanelModel) moveSelectedColumn(delta int) {
+	visible := m.visibleColumns()
+	target := m.selectedColumn + delta
+	if target < 0 || target >= len(visible) {
+		return
+	}
+	from := slices.Index(m.columns, visible[m.selectedColumn])
+	to := slices.Index(m.columns, visible[target])
+	m.columns[from], m.columns[to] = m.columns[to], m.columns[from]
+	m.selectedColumn = target
+	m.refreshColumns()
+}
+
+// the columns that are shown, in order
+func (m ResultsPanelModel) visibleColumns() []string {
+	var visible []string
+	for _, c := range m.columns {
+		if !m.hidden[c] {
+			visible = append(visible, c)
+		}
+	}
+	return visible
+}
+
+func (m *ResultsPanelModel) refreshColumns() {
+	m.selectedColumn = min(m.selectedColumn, max(len(m.visibleColumns())-1, 0))
+	m.setColumns()
+	m.scrollToSelectedColumn()
+}
+
+// sets the table columns, marking the selected column and any sort
+func (m *ResultsPanelModel) setColumns() {
+	cols := []table.Column{}
+	m.columnWidths = nil
+	for i, c := range m.visibleColumns() {
+		title := c
+		sortColumn, sortDesc := m.sortColumn, m.sortDesc
+		if q := m.data.TableQuery; q != nil {
+			sortColumn, sortDesc = q.OrderBy, q.Desc
+		}
+		if sortColumn == c {
+			if sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		if i == m.selectedColumn {
+			title = "[" + title + "]"
+		}
+		w := max(getColumnWidth(c, *m.data), lipgloss.Width(title)+1)
+		m.columnWidths = append(m.columnWidths, w)
+		cols = append(cols, table.NewColumn(c, title, w).WithFiltered(true))
+	}
 	m.table = m.table.
-		WithRows(rows).
-		WithColumns(cols)
+		WithColumns(cols).
+		WithHorizontalFreezeColumnCount(min(m.frozen, len(cols)))
+}
 
-	m.loading = false
-	m.SetSize(m.width, m.height)
+func (m *ResultsPanelModel) selectColumn(index int) {
+	if m.data == nil || index < 0 || index >= len(m.visibleColumns()) {
+		return
+	}
+	m.selectedColumn = index
+	m.setColumns()
+	m.scrollToSelectedColumn()
+}
+
+// scrolls the table horizontally until the selected column is visible
+func (m *ResultsPanelModel) scrollToSelectedColumn() {
+	frozen := m.frozen
+	if m.selectedColumn < frozen || m.selectedColumn >= len(m.columnWidths) {
+		return
+	}
+	for m.table.GetHorizontalScrollColumnOffset() > m.selectedColumn-frozen {
+		m.table = m.table.ScrollLeft()
+	}
+	for {
+		offset := m.table.GetHorizontalScrollColumnOffset()
+		// the left border and overflow indicator, then each column and its border
+		visibleWidth := 3
+		for i := 0; i < frozen && i < len(m.columnWidths); i++ {
+			visibleWidth += m.columnWidths[i] + 1
+		}
+		for i := frozen + offset; i <= m.selectedColumn; i++ {
+			visibleWidth += m.columnWidths[i] + 1
+		}
+		if visibleWidth <= m.width-1 {
+			return
+		}
+		m.table = m.table.ScrollRight()
+		if m.table.GetHorizontalScrollColumnOffset() == offset {
+			return
+		}
+	}
 }
 
 func (m *ResultsPanelModel) SetSize(w, h int) {
@@ -120,6 +340,90 @@ func (m ResultsPanelModel) GetSelectedRow() map[string]any {
 	return m.table.HighlightedRow().Data
 }
 
+// the position of the highlighted row in the (filtered and sorted) rows, and the number of rows
+func (m ResultsPanelModel) GetSelectedIndex() (int, int) {
+	return m.table.GetHighlightedRowIndex(), len(m.table.GetVisibleRows())
+}
+
+// highlights the row at the position in the (filtered and sorted) rows
+func (m *ResultsPanelModel) SelectRow(index int) {
+	m.table = m.table.WithHighlightedRow(index)
+}
+
+// moves the highlight up (n < 0) or down by n rows
+func (m *ResultsPanelModel) ScrollBy(n int) {
+	m.SelectRow(m.table.GetHighlightedRowIndex() + n)
+}
+
+// the index of the row shown at y (from the top of the panel), or -1 if there isn't one
+func (m ResultsPanelModel) RowAt(y int) int {
+	return tableRowAt(m.table, y)
+}
+
+// the table the results were selected from, empty if they don't come from a single table
+func (m ResultsPanelModel) GetTable() string {
+	if m.data == nil {
+		return ""
+	}
+	return m.data.Table
+}
+
+// summarises the values of the selected column in the fetched rows
+func (m ResultsPanelModel) DescribeSelectedColumn() (db.ColumnStats, bool) {
+	col := m.GetSelectedColumn()
+	if m.data == nil || col == "" {
+		return db.ColumnStats{}, false
+	}
+	return db.ComputeColumnStats(m.data, col), true
+}
+
+// the name of the selected column
+func (m ResultsPanelModel) GetSelectedColumn() string {
+	visible := m.visibleColumns()
+	if m.selectedColumn >= len(visible) {
+		return ""
+	}
+	return visible[m.selectedColumn]
+}
+
+// the query for the table data being shown, nil if the results aren't table data
+func (m ResultsPanelModel) GetTableQuery() *db.TableQuery {
+	if m.data == nil {
+		return nil
+	}
+	return m.data.TableQuery
+}
+
+// reports whether there is another page of table data
+func (m ResultsPanelModel) HasMore() bool {
+	return m.data != nil && m.data.HasMore
+}
+
+func (m ResultsPanelModel) GetColumns() []string {
+	if m.data == nil {
+		return nil
+	}
+	return m.data.Columns
+}
+
+func (m ResultsPanelModel) GetData() *db.Data {
+	return m.data
+}
+
+// the rows in the order they are shown, without those filtered out
+func (m ResultsPanelModel) GetVisibleRows() []map[string]any {
+	var rows []map[string]any
+	for _, row := range m.table.GetVisibleRows() {
+		rows = append(rows, row.Data)
+	}
+	return rows
+}
+
+// reports whether the filter input has focus, so keys are being typed into it
+func (m ResultsPanelModel) IsFiltering() bool {
+	return m.table.GetIsFilterInputFocused()
+}
+
 func (m ResultsPanelModel) View() string {
 	panelStyle := style.BasePanelStyle.
 		Width(m.width).
@@ -129,7 +433,14 @@ func (m ResultsPanelModel) View() string {
 		panelStyle = panelStyle.BorderForeground(colour.BorderActive)
 	}
 
-	title := style.Title(m.width-2, m.active).Render("results")
+	titleText := "results"
+	if q := m.GetTableQuery(); q != nil {
+		titleText = fmt.Sprintf("results: %s (page %d)", q.Description(), q.Page+1)
+	}
+	if hidden := len(m.columns) - len(m.visibleColumns()); hidden > 0 {
+		titleText += fmt.Sprintf(" [%d hidden]", hidden)
+	}
+	title := style.Title(m.width-2, m.active).Render(truncate.StringWithTail(titleText, uint(max(m.width-4, 0)), "…"))
 	content := lipgloss.JoinVertical(lipgloss.Bottom, m.table.View())
 	if m.loading {
 		content = m.spinner.View()
@@ -147,8 +458,8 @@ func getColumnWidth(col string, data db.Data) int {
 		}
 	}
 	for _, r := range data.Rows {
-		if len(fmt.Sprintf("%v", r[col])) > maxLen {
-			maxLen = len(r[col].(string))
+		if l := len(fmt.Sprintf("%v", r[col])); l > maxLen {
+			maxLen = l
 		}
 	}
 	padding := 1
@@ -2,22 +2,39 @@ package component
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/wheelibin/qrypad/internal/colour"
-	"github.com/wheelibin/qrypad/internal/constants"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/db"
 )
 
+// how long a message is shown in the status bar
+const statusMessageDuration = 3 * time.Second
+
 type StatusBarModel struct {
 	width             int
 	height            int
-	text              string
 	connectedDatabase string
+	connection        string
+	readOnly          bool
+	inTransaction     bool
+	txStatements      int
+	panel             string
+	bufferName        string
+	bufferDirty       bool
+	// the last statement's duration and the rows it returned or changed, empty before the first one
+	lastStatement string
+	// a transient message, and its id so an older message's expiry doesn't clear a newer one
+	message   string
+	messageID int
 }
 
-func NewStatusBarModel(dbAlias string) StatusBarModel {
-	return StatusBarModel{connectedDatabase: dbAlias}
+func NewStatusBarModel(dbAlias string, connection string, readOnly bool) StatusBarModel {
+	return StatusBarModel{connectedDatabase: dbAlias, connection: connection, readOnly: readOnly}
 }
 
 func (m StatusBarModel) Init() tea.Cmd {
@@ -26,11 +43,13 @@ func (m StatusBarModel) Init() tea.Cmd {
 
 func (m StatusBarModel) Update(msg tea.Msg) (StatusBarModel, tea.Cmd) {
 	// log.Println("statusBar.model::Update", msg)
-	var (
-		cmds []tea.Cmd
-	)
-
-	return m, tea.Batch(cmds...)
+	switch msg := msg.(type) {
+	case commands.StatusMessageExpiredMsg:
+		if msg.ID == m.messageID {
+			m.message = ""
+		}
+	}
+	return m, nil
 }
 
 func (m *StatusBarModel) SetSize(w, h int) {
@@ -38,8 +57,43 @@ func (m *StatusBarModel) SetSize(w, h int) {
 	m.height = h
 }
 
-func (m *StatusBarModel) SetText(text string) {
-	m.text = text
+// shows a message, such as "saved", for a few seconds
+func (m *StatusBarModel) ShowMessage(text string) tea.Cmd {
+	m.message = text
+	m.messageID++
+	return commands.ExpireStatusMessage(m.messageID, statusMessageDuration)
+}
+
+func (m *StatusBarModel) SetTransaction(open bool, statements int) {
+	m.inTransaction = open
+	m.txStatements = statements
+}
+
+func (m *StatusBarModel) SetPanel(name string) {
+	m.panel = name
+}
+
+func (m *StatusBarModel) SetBuffer(name string, dirty bool) {
+	m.bufferName = name
+	m.bufferDirty = dirty
+}
+
+// records the duration and row count of the statement that returned the data
+func (m *StatusBarModel) SetResult(data *db.Data) {
+	rows := fmt.Sprintf("%d rows", len(data.Rows))
+	if data.Query == "" {
+		rows = fmt.Sprintf("%d rows affected", data.RowsAffected)
+	} else if len(data.Rows) == 1 {
+		rows = "1 row"
+	}
+	m.lastStatement = fmt.Sprintf("%s in %s", rows, formatDuration(data.Duration))
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
 }
 
 func (m StatusBarModel) View() string {
@@ -47,8 +101,52 @@ func (m StatusBarModel) View() string {
 		Background(colour.StatusBarBG).
 		Foreground(colour.StatusBarFG).
 		Padding(0, 2)
-	barStyle = barStyle.Width(m.width)
 	barStyle = barStyle.Height(m.height)
 
-	return barStyle.Render(fmt.Sprintf("QryPad - %s [%s]", constants.AppDesc, m.connectedDatabase))
+	badge := ""
+	if m.readOnly {
+		badge = lipgloss.NewStyle().
+			Background(colour.ReadOnlyBadgeBG).
+			Foreground(colour.ReadOnlyBadgeFG).
+			Bold(true).
+			Padding(0, 1).
+			Render("READ ONLY")
+	}
+	if m.inTransaction {
+		badge += lipgloss.NewStyle().
+			Background(colour.TransactionBadgeBG).
+			Foreground(colour.TransactionBadgeFG).
+			Bold(true).
+			Padding(0, 1).
+			Render(fmt.Sprintf("IN TRANSACTION (%d statements)", m.txStatements))
+	}
+	barStyle = barStyle.Width(m.width - lipgloss.Width(badge))
+
+	parts := []string{fmt.Sprintf("%s (%s)", m.connectedDatabase, m.connection)}
+	if m.panel != "" {
+		parts = append(parts, m.panel)
+	}
+	if m.bufferName != "" {
+		state := "saved"
+		if m.bufferDirty {
+			state = "modified"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", m.bufferName, state))
+	}
+	if m.lastStatement != "" {
+		parts = append(parts, m.lastStatement)
+	}
+	text := strings.Join(parts, " │ ")
+
+	// the message goes on the right, and the details are cut short to make room for it
+	innerWidth := m.width - lipgloss.Width(badge) - 4
+	if m.message != "" {
+		message := lipgloss.NewStyle().Background(colour.StatusBarBG).Foreground(colour.StatusBarMessageFG).Bold(true).Render(m.message)
+		text = fitText(text, max(innerWidth-lipgloss.Width(message)-2, 1))
+		text += strings.Repeat(" ", max(innerWidth-lipgloss.Width(text)-lipgloss.Width(message), 1)) + message
+	} else {
+		text = fitText(text, max(innerWidth, 1))
+	}
+
+	return badge + barStyle.Render(text)
 }
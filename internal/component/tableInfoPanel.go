@@ -152,6 +152,21 @@ func (m TableInfoPanelModel) GetSelectedRow() map[string]any {
 	return m.table.HighlightedRow().Data
 }
 
+// highlights the row at the position in the (filtered) rows
+func (m *TableInfoPanelModel) SelectRow(index int) {
+	m.table = m.table.WithHighlightedRow(index)
+}
+
+// moves the highlight up (n < 0) or down by n rows
+func (m *TableInfoPanelModel) ScrollBy(n int) {
+	m.SelectRow(m.table.GetHighlightedRowIndex() + n)
+}
+
+// the index of the row shown at y (from the top of the panel), or -1 if there isn't one
+func (m TableInfoPanelModel) RowAt(y int) int {
+	return tableRowAt(m.table, y)
+}
+
 func (m TableInfoPanelModel) View() string {
 	var panelStyle = style.BasePanelStyle
 	panelStyle = panelStyle.Width(m.width)
@@ -67,8 +67,7 @@ func (m TablePanelModel) Update(msg tea.Msg) (TablePanelModel, tea.Cmd) {
 		for _, e := range m.table.GetLastUpdateUserEvents() {
 			switch e.(type) {
 			case table.UserEventHighlightedIndexChanged:
-				m.selectedTable = m.table.HighlightedRow().Data["name"].(string)
-				cmds = append(cmds, commands.TableSelectionChanged(m.selectedTable))
+				cmds = append(cmds, m.selectionChanged())
 			}
 		}
 	}
@@ -108,6 +107,31 @@ func (m TablePanelModel) GetSelectedTable() string {
 	return m.selectedTable
 }
 
+// highlights the row at the position in the (filtered) rows
+func (m *TablePanelModel) SelectRow(index int) tea.Cmd {
+	m.table = m.table.WithHighlightedRow(index)
+	return m.selectionChanged()
+}
+
+// moves the highlight up (n < 0) or down by n rows
+func (m *TablePanelModel) ScrollBy(n int) tea.Cmd {
+	return m.SelectRow(m.table.GetHighlightedRowIndex() + n)
+}
+
+// the index of the row shown at y (from the top of the panel), or -1 if there isn't one
+func (m TablePanelModel) RowAt(y int) int {
+	return tableRowAt(m.table, y)
+}
+
+func (m *TablePanelModel) selectionChanged() tea.Cmd {
+	name, _ := m.table.HighlightedRow().Data["name"].(string)
+	if name == "" || name == m.selectedTable {
+		return nil
+	}
+	m.selectedTable = name
+	return commands.TableSelectionChanged(m.selectedTable)
+}
+
 func (m *TablePanelModel) SetActive(active bool) {
 	m.table = m.table.Focused(active)
 	m.active = active
@@ -0,0 +1,109 @@
+package component
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+	"github.com/wheelibin/qrypad/internal/commands"
+	"github.com/wheelibin/qrypad/internal/style"
+)
+
+type MessageLevel int
+
+const (
+	MessageInfo MessageLevel = iota
+	MessageWarning
+	MessageError
+)
+
+func (l MessageLevel) String() string {
+	switch l {
+	case MessageWarning:
+		return "warning"
+	case MessageError:
+		return "error"
+	}
+	return "info"
+}
+
+func (l MessageLevel) colour() lipgloss.TerminalColor {
+	switch l {
+	case MessageWarning:
+		return colour.Warning
+	case MessageError:
+		return colour.Error
+	}
+	return colour.Info
+}
+
+const (
+	// how long a toast is shown
+	toastDuration = 5 * time.Second
+	// the most toasts shown at once, older ones are dropped
+	maxToasts = 3
+)
+
+type toast struct {
+	id    int
+	level MessageLevel
+	text  string
+}
+
+// notifications that are shown for a few seconds without taking the keys
+type ToastsModel struct {
+	width  int
+	toasts []toast
+	nextID int
+}
+
+func NewToastsModel() ToastsModel {
+	return ToastsModel{}
+}
+
+func (m ToastsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ToastsModel) Update(msg tea.Msg) (ToastsModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case commands.ToastExpiredMsg:
+		for i, t := range m.toasts {
+			if t.id == msg.ID {
+				m.toasts = append(m.toasts[:i:i], m.toasts[i+1:]...)
+				break
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *ToastsModel) SetSize(w int) {
+	m.width = w
+}
+
+func (m *ToastsModel) Add(level MessageLevel, text string) tea.Cmd {
+	m.nextID++
+	m.toasts = append(m.toasts, toast{id: m.nextID, level: level, text: text})
+	if len(m.toasts) > maxToasts {
+		m.toasts = m.toasts[len(m.toasts)-maxToasts:]
+	}
+	return commands.ExpireToast(m.nextID, toastDuration)
+}
+
+func (m ToastsModel) IsEmpty() bool {
+	return len(m.toasts) == 0
+}
+
+func (m ToastsModel) View() string {
+	var views []string
+	for _, t := range m.toasts {
+		views = append(views, style.BasePanelStyle.
+			BorderForeground(t.level.colour()).
+			Width(m.width).
+			Padding(0, 1).
+			Render(lipgloss.NewStyle().Foreground(t.level.colour()).Bold(true).Render(t.level.String())+"  "+t.text))
+	}
+	return lipgloss.JoinVertical(lipgloss.Right, views...)
+}
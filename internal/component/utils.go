@@ -2,8 +2,14 @@ package component
 
 import (
 	"strings"
+	"unicode"
+
+	"github.com/evertras/bubble-table/table"
 )
 
+// the first row of a panel's table is below the panel's border and title, and the table's border, header and separator
+const tableFirstRowY = 5
+
 func getStatementAtCursor(text string, cursorLine int) string {
 	lines := strings.Split(text, "\n")
 	if cursorLine < 0 || cursorLine >= len(lines) {
@@ -38,3 +44,69 @@ func getStatementAtCursor(text string, cursorLine int) string {
 
 	return "" // statement not found
 }
+
+// the length in characters of the sql token at column: a word, a quoted string or identifier, or a single character
+func tokenLength(line string, column int) int {
+	runes := []rune(line)
+	if column < 0 || column >= len(runes) {
+		return 0
+	}
+	isWord := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	switch c := runes[column]; {
+	case isWord(c):
+		end := column + 1
+		for end < len(runes) && isWord(runes[end]) {
+			end++
+		}
+		return end - column
+	case c == '\'' || c == '"' || c == '`':
+		for end := column + 1; end < len(runes); end++ {
+			if runes[end] == c {
+				return end - column + 1
+			}
+		}
+		return len(runes) - column
+	}
+	return 1
+}
+
+// the index (in the visible rows) of the table row shown at y, counted from the top of the panel, or -1 if there isn't one
+func tableRowAt(t table.Model, y int) int {
+	start, end := t.VisibleIndices()
+	i := start + y - tableFirstRowY
+	if y < tableFirstRowY || i > end {
+		return -1
+	}
+	return i
+}
+
+// matches the characters of pattern, in order but not necessarily together, in text ignoring case. It returns the
+// positions of the matched characters, and a score that is higher when they are together or start words.
+func fuzzyMatch(pattern string, text string) ([]int, int, bool) {
+	runes := []rune(strings.ToLower(text))
+	var positions []int
+	score := 0
+	i := 0
+	for _, p := range strings.ToLower(pattern) {
+		if unicode.IsSpace(p) {
+			continue
+		}
+		for i < len(runes) && runes[i] != p {
+			i++
+		}
+		if i == len(runes) {
+			return nil, 0, false
+		}
+		score++
+		if len(positions) > 0 && positions[len(positions)-1] == i-1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			score += 3
+		}
+		positions = append(positions, i)
+		i++
+	}
+	return positions, score, true
+}
@@ -0,0 +1,1313 @@
+package component
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/wheelibin/qrypad/internal/colour"
+)
+
+// the editor mode that turns on vim-style editing of the query buffers
+const EditorModeVim = "vim"
+
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual
+	vimVisualLine
+)
+
+func (m vimMode) String() string {
+	switch m {
+	case vimInsert:
+		return "insert"
+	case vimVisual:
+		return "visual"
+	case vimVisualLine:
+		return "visual line"
+	}
+	return "normal"
+}
+
+// the text objects that can follow i or a, such as the w of diw
+const vimTextObjects = "wWp\"'`()b{}B[]<>"
+
+// commands that are short for an operator and a motion, x is dl
+var vimShorthands = map[string][2]string{
+	"x": {"d", "l"},
+	"X": {"d", "h"},
+	"D": {"d", "$"},
+	"C": {"c", "$"},
+	"s": {"c", "l"},
+	"S": {"c", "cc"},
+	"Y": {"y", "yy"},
+}
+
+var vimMotions = map[string]bool{
+	"h": true, "left": true, "backspace": true, "l": true, "right": true, " ": true,
+	"j": true, "down": true, "k": true, "up": true,
+	"w": true, "W": true, "b": true, "B": true, "e": true, "E": true,
+	"0": true, "home": true, "^": true, "$": true, "end": true, "gg": true, "G": true,
+	"{": true, "}": true, "f": true, "t": true, "F": true, "T": true, ";": true, ",": true, "%": true,
+}
+
+var vimNormalCommands = map[string]bool{
+	"x": true, "X": true, "D": true, "C": true, "s": true, "S": true, "Y": true,
+	"r": true, "J": true, "p": true, "P": true, "o": true, "O": true, "~": true,
+	"i": true, "a": true, "I": true, "A": true, "v": true, "V": true,
+	"u": true, "ctrl+r": true, ".": true, "esc": true, "/": true, "n": true, "N": true,
+}
+
+var vimVisualCommands = map[string]bool{
+	"d": true, "x": true, "X": true, "D": true, "y": true, "Y": true,
+	"c": true, "s": true, "C": true, "S": true, "~": true, "J": true, "p": true, "P": true,
+	"o": true, "v": true, "V": true, "esc": true,
+}
+
+// text that was yanked or deleted, linewise text is put on lines of its own
+type vimRegister struct {
+	text     string
+	linewise bool
+}
+
+// the vim editing state of the query panel, used when the editor mode is vim
+type vimEditor struct {
+	enabled bool
+	mode    vimMode
+	// the keys of the command being typed, such as the d of dw
+	typed []tea.KeyMsg
+	// the keys of the last change, repeated by ., and of the change being typed in insert mode
+	lastChange []tea.KeyMsg
+	recording  []tea.KeyMsg
+	replaying  bool
+	register   vimRegister
+	// where the visual selection started, as an offset into the text
+	anchor int
+	// the last f, t, F or T and its character, repeated by ; and ,
+	lastFind     string
+	lastFindChar rune
+	// the buffer when insert mode was entered, so the whole insert is undone together
+	insertStart bufferState
+}
+
+// a command typed in normal or visual mode
+type vimCommand struct {
+	// 1 when no count was typed
+	count   int
+	counted bool
+	// d, c or y, empty for a motion or a command on its own
+	operator string
+	// the motion, text object or command, such as w, gg, iw, dd or x
+	key string
+	// the character for f, t, F, T and r
+	char rune
+}
+
+// parses the keys of a command, done is false while more keys are needed and ok is false when they aren't a command
+func parseVimCommand(keys []string, visual bool) (cmd vimCommand, done bool, ok bool) {
+	cmd.count = 1
+	i := 0
+	readCount := func() {
+		n := 0
+		for i < len(keys) && len(keys[i]) == 1 && keys[i][0] >= '0' && keys[i][0] <= '9' && (keys[i] != "0" || n > 0) {
+			n = n*10 + int(keys[i][0]-'0')
+			i++
+		}
+		if n > 0 {
+			cmd.count *= n
+			cmd.counted = true
+		}
+	}
+
+	readCount()
+	if i == len(keys) {
+		return cmd, false, true
+	}
+	if !visual && (keys[i] == "d" || keys[i] == "c" || keys[i] == "y") {
+		cmd.operator = keys[i]
+		i++
+		readCount()
+		if i == len(keys) {
+			return cmd, false, true
+		}
+	}
+
+	k := keys[i]
+	i++
+	// the commands that take another key
+	switch {
+	case k == cmd.operator:
+		cmd.key = k + k
+		return cmd, true, true
+
+	case k == "g":
+		if i == len(keys) {
+			return cmd, false, true
+		}
+		cmd.key = "gg"
+		return cmd, true, keys[i] == "g"
+
+	case k == "f" || k == "t" || k == "F" || k == "T" || (k == "r" && cmd.operator == "" && !visual):
+		if i == len(keys) {
+			return cmd, false, true
+		}
+		r := []rune(keys[i])
+		if len(r) != 1 {
+			return cmd, true, false
+		}
+		cmd.key, cmd.char = k, r[0]
+		return cmd, true, true
+
+	case (k == "i" || k == "a") && (cmd.operator != "" || visual):
+		if i == len(keys) {
+			return cmd, false, true
+		}
+		cmd.key = k + keys[i]
+		return cmd, true, len(keys[i]) == 1 && strings.Contains(vimTextObjects, keys[i])
+	}
+
+	cmd.key = k
+	switch {
+	case vimMotions[k]:
+		return cmd, true, true
+	case visual:
+		return cmd, true, vimVisualCommands[k]
+	case cmd.operator == "":
+		return cmd, true, vimNormalCommands[k]
+	}
+	return cmd, true, false
+}
+
+// handles a key in the active buffer, the key is taken from the ui when it's part of a vim command
+func (m *QueryPanelModel) vimKey(buffer *queryBuffer, msg tea.KeyMsg) tea.Cmd {
+	v := &m.vim
+	ta := &buffer.textarea
+
+	if v.mode == vimInsert {
+		if v.recording != nil {
+			v.recording = append(v.recording, msg)
+		}
+		if msg.Type == tea.KeyEsc {
+			m.tookKey = true
+			m.leaveInsert(buffer)
+			return nil
+		}
+		var cmd tea.Cmd
+		*ta, cmd = ta.Update(msg)
+		return cmd
+	}
+
+	v.typed = append(v.typed, msg)
+	keys := make([]string, len(v.typed))
+	for i, k := range v.typed {
+		keys[i] = k.String()
+	}
+	visual := v.mode == vimVisual || v.mode == vimVisualLine
+	cmd, done, ok := parseVimCommand(keys, visual)
+	if !ok {
+		// a key that doesn't finish a command cancels it, and is only left for the ui when it's on its own
+		m.tookKey = len(v.typed) > 1
+		v.typed = nil
+		return nil
+	}
+	m.tookKey = true
+	if !done {
+		return nil
+	}
+	typed := v.typed
+	v.typed = nil
+
+	switch cmd.key {
+	case "u":
+		for i := 0; i < cmd.count && buffer.undoChange(); i++ {
+		}
+		m.vimClampCursor(buffer)
+		return nil
+	case "ctrl+r":
+		for i := 0; i < cmd.count && buffer.redoChange(); i++ {
+		}
+		m.vimClampCursor(buffer)
+		return nil
+	case "/":
+		return m.openFind(buffer, false)
+	case "n", "N":
+		for i := 0; i < cmd.count; i++ {
+			m.findAgain(buffer, cmd.key == "n")
+		}
+		m.vimClampCursor(buffer)
+		return nil
+	case ".":
+		if v.replaying {
+			return nil
+		}
+		change := v.lastChange
+		if cmd.counted {
+			// the count replaces the one the change was made with
+			change = append(runeKeys(strconv.Itoa(cmd.count)), withoutCount(change)...)
+		}
+		v.replaying = true
+		var cmds []tea.Cmd
+		for _, k := range change {
+			cmds = append(cmds, m.vimKey(buffer, k))
+		}
+		v.replaying = false
+		return tea.Batch(cmds...)
+	}
+
+	text, offset := textOf(*ta)
+	before := bufferState{value: string(text), cursor: offset}
+	if visual {
+		// a visual change is repeated on the same amount of text from the cursor
+		typed = append(v.reselectKeys(text, offset), typed...)
+		text, offset = v.visualCommand(text, offset, cmd)
+	} else {
+		text, offset = v.normalCommand(text, offset, cmd)
+	}
+	setText(ta, text, offset)
+
+	switch {
+	case v.mode == vimInsert:
+		v.insertStart = before
+		if !v.replaying {
+			v.recording = append([]tea.KeyMsg{}, typed...)
+		}
+	case ta.Value() != before.value:
+		buffer.pushUndo(before)
+		if !v.replaying {
+			v.lastChange = typed
+		}
+	}
+
+	// the textarea scrolls to the cursor as it updates
+	var taCmd tea.Cmd
+	*ta, taCmd = ta.Update(nil)
+	return taCmd
+}
+
+// the keys that select as much text as the visual selection does, starting from the cursor
+func (v vimEditor) reselectKeys(text []rune, offset int) []tea.KeyMsg {
+	start, end := v.selection(text, offset)
+	startLine, _ := lineCol(text, start)
+	endLine, endCol := lineCol(text, max(end-1, start))
+
+	keys := "v"
+	if v.mode == vimVisualLine {
+		keys = "V"
+	}
+	if endLine > startLine {
+		keys += strconv.Itoa(endLine-startLine) + "j"
+		if v.mode == vimVisual {
+			keys += "0"
+			if endCol > 0 {
+				keys += strconv.Itoa(endCol) + "l"
+			}
+		}
+	} else if v.mode == vimVisual && end-start > 1 {
+		keys += strconv.Itoa(end-start-1) + "l"
+	}
+	return runeKeys(keys)
+}
+
+// the keys of the change without the count typed before it, or before its motion, as in 3dw or d3w
+func withoutCount(change []tea.KeyMsg) []tea.KeyMsg {
+	isCount := func(i int, first bool) bool {
+		k := change[i].String()
+		return len(k) == 1 && k[0] >= '0' && k[0] <= '9' && (k != "0" || !first)
+	}
+	var keys []tea.KeyMsg
+	i := 0
+	for ; i < len(change) && isCount(i, i == 0); i++ {
+	}
+	if i < len(change) {
+		if k := change[i].String(); k == "d" || k == "c" || k == "y" {
+			keys = append(keys, change[i])
+			i++
+			for first := true; i < len(change) && isCount(i, first); i, first = i+1, false {
+			}
+		}
+	}
+	return append(keys, change[i:]...)
+}
+
+// the keys that type s
+func runeKeys(s string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for _, r := range s {
+		keys = append(keys, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return keys
+}
+
+// goes back to normal mode, the whole insert is undone in one go
+func (m *QueryPanelModel) leaveInsert(buffer *queryBuffer) {
+	v := &m.vim
+	v.mode = vimNormal
+	if v.recording != nil && !v.replaying {
+		v.lastChange = v.recording
+	}
+	v.recording = nil
+	if buffer.textarea.Value() != v.insertStart.value {
+		buffer.pushUndo(v.insertStart)
+	}
+	// the cursor moves back onto the last character typed
+	text, offset := textOf(buffer.textarea)
+	if offset > lineStart(text, offset) {
+		offset--
+	}
+	setText(&buffer.textarea, text, offset)
+}
+
+// makes what has been typed in insert mode so far an undo step of its own
+func (m *QueryPanelModel) vimBreakInsert(buffer *queryBuffer) {
+	if state := buffer.state(); state.value != m.vim.insertStart.value {
+		buffer.pushUndo(m.vim.insertStart)
+		m.vim.insertStart = state
+	}
+}
+
+// keeps the cursor on a character, as it can't be past the end of the line in normal mode
+func (m *QueryPanelModel) vimClampCursor(buffer *queryBuffer) {
+	text, offset := textOf(buffer.textarea)
+	setText(&buffer.textarea, text, clampNormal(text, offset))
+}
+
+func (v *vimEditor) normalCommand(text []rune, offset int, cmd vimCommand) ([]rune, int) {
+	if short, ok := vimShorthands[cmd.key]; ok {
+		cmd.operator, cmd.key = short[0], short[1]
+	}
+	if cmd.operator != "" {
+		return v.operate(text, offset, cmd)
+	}
+
+	switch cmd.key {
+	case "esc":
+	case "i":
+		v.mode = vimInsert
+		return text, offset
+	case "a":
+		v.mode = vimInsert
+		return text, min(offset+1, lineEnd(text, offset))
+	case "I":
+		v.mode = vimInsert
+		return text, firstNonBlank(text, offset)
+	case "A":
+		v.mode = vimInsert
+		return text, lineEnd(text, offset)
+	case "o", "O":
+		// the new line has the same indentation as the current one
+		indent := string(text[lineStart(text, offset):firstNonBlank(text, offset)])
+		v.mode = vimInsert
+		if cmd.key == "o" {
+			at := lineEnd(text, offset)
+			return insertText(text, at, "\n"+indent), at + 1 + len([]rune(indent))
+		}
+		at := lineStart(text, offset)
+		return insertText(text, at, indent+"\n"), at + len([]rune(indent))
+	case "v", "V":
+		v.mode = vimVisual
+		if cmd.key == "V" {
+			v.mode = vimVisualLine
+		}
+		v.anchor = offset
+	case "p", "P":
+		text, offset = v.put(text, offset, cmd.key == "P", cmd.count)
+	case "J":
+		text, offset = joinLines(text, offset, max(cmd.count, 2)-1)
+	case "r":
+		end := lineEnd(text, offset)
+		if offset+cmd.count <= end {
+			text = append([]rune{}, text...)
+			for i := offset; i < offset+cmd.count; i++ {
+				text[i] = cmd.char
+			}
+			offset += cmd.count - 1
+		}
+	case "~":
+		end := min(offset+cmd.count, lineEnd(text, offset))
+		text = toggleCase(text, offset, end)
+		offset = end
+	default:
+		if motion, ok := v.motion(text, offset, cmd); ok {
+			offset = motion.target
+		}
+	}
+	return text, clampNormal(text, offset)
+}
+
+// applies d, c or y to the text the command's motion or text object covers
+func (v *vimEditor) operate(text []rune, offset int, cmd vimCommand) ([]rune, int) {
+	var start, end int
+	linewise := false
+
+	switch {
+	case cmd.key == cmd.operator+cmd.operator:
+		// dd, cc and yy work on count lines
+		line, _ := lineCol(text, offset)
+		last := offsetOfLine(text, line+cmd.count-1)
+		start, end = wholeLines(text, offset, last)
+		linewise = true
+
+	case len(cmd.key) == 2 && (cmd.key[0] == 'i' || cmd.key[0] == 'a'):
+		var ok bool
+		if start, end, linewise, ok = textObject(text, offset, cmd.key); !ok {
+			return text, offset
+		}
+
+	default:
+		if cmd.operator == "c" && (cmd.key == "w" || cmd.key == "W") && offset < len(text) && !unicode.IsSpace(text[offset]) {
+			// cw changes to the end of the word, like ce
+			bigWord := cmd.key == "W"
+			if bigWord {
+				cmd.key = "E"
+			} else {
+				cmd.key = "e"
+			}
+			if offset+1 == len(text) || charClass(text[offset+1], bigWord) != charClass(text[offset], bigWord) {
+				// except on the last character of a word, where e would go on to the end of the next one
+				cmd.count--
+			}
+		}
+		motion, ok := v.motion(text, offset, cmd)
+		if !ok {
+			return text, offset
+		}
+		if (cmd.key == "w" || cmd.key == "W") && motion.target > offset {
+			// a word motion that ends at the start of a later line stops at the end of the line before it
+			if nl := lastIndex(text[offset:motion.target], '\n'); nl >= 0 && strings.TrimSpace(string(text[offset+nl:motion.target])) == "" {
+				motion.target = offset + nl
+			}
+		}
+		start, end = min(offset, motion.target), max(offset, motion.target)
+		if motion.linewise {
+			start, end = wholeLines(text, start, end)
+			linewise = true
+		} else if motion.inclusive && end < len(text) {
+			end++
+		}
+	}
+
+	switch cmd.operator {
+	case "y":
+		v.yank(text, start, end, linewise)
+		if !linewise {
+			offset = start
+		}
+		return text, clampNormal(text, offset)
+	case "c":
+		v.yank(text, start, end, linewise)
+		if linewise && end > start && text[end-1] == '\n' {
+			// the lines are emptied rather than removed
+			end--
+		}
+		v.mode = vimInsert
+		return removeText(text, start, end), start
+	}
+	text = v.cut(text, start, end, linewise)
+	if linewise {
+		return text, firstNonBlank(text, min(start, len(text)))
+	}
+	return text, clampNormal(text, start)
+}
+
+func (v *vimEditor) visualCommand(text []rune, offset int, cmd vimCommand) ([]rune, int) {
+	start, end := v.selection(text, offset)
+	linewise := v.mode == vimVisualLine
+
+	switch cmd.key {
+	case "esc":
+		v.mode = vimNormal
+		return text, clampNormal(text, offset)
+	case "v", "V":
+		mode := vimVisual
+		if cmd.key == "V" {
+			mode = vimVisualLine
+		}
+		if v.mode == mode {
+			v.mode = vimNormal
+			return text, clampNormal(text, offset)
+		}
+		v.mode = mode
+		return text, offset
+	case "o":
+		v.anchor, offset = offset, v.anchor
+		return text, offset
+	case "X", "D", "Y", "C", "S":
+		// the upper case commands work on whole lines
+		start, end = wholeLines(text, start, max(end-1, start))
+		linewise = true
+	}
+
+	if len(cmd.key) == 2 && (cmd.key[0] == 'i' || cmd.key[0] == 'a') {
+		// the selection becomes the text object
+		if s, e, lw, ok := textObject(text, offset, cmd.key); ok && e > s {
+			v.anchor, offset = s, e-1
+			if lw {
+				v.mode = vimVisualLine
+			}
+		}
+		return text, offset
+	}
+
+	switch cmd.key {
+	case "d", "x", "X", "D":
+		text = v.cut(text, start, end, linewise)
+		offset = start
+		if linewise {
+			offset = firstNonBlank(text, min(start, len(text)))
+		}
+	case "y", "Y":
+		v.yank(text, start, end, linewise)
+		offset = start
+	case "c", "s", "C", "S":
+		v.yank(text, start, end, linewise)
+		if linewise && end > start && text[end-1] == '\n' {
+			end--
+		}
+		v.mode = vimInsert
+		return removeText(text, start, end), start
+	case "~":
+		text = toggleCase(text, start, end)
+		offset = start
+	case "J":
+		startLine, _ := lineCol(text, start)
+		endLine, _ := lineCol(text, max(end-1, start))
+		text, offset = joinLines(text, start, max(endLine-startLine, 1))
+	case "p", "P":
+		// the selection is replaced by the register, and put in it
+		register := v.register
+		text = v.cut(text, start, end, linewise)
+		replaced := v.register
+		v.register = register
+		switch {
+		case start > len(text):
+			// the last line went with the newline before it, so the register goes below the line before
+			if register.linewise {
+				text, offset = v.put(text, len(text), false, 1)
+			} else {
+				text = insertText(text, len(text), "\n")
+				text, offset = v.put(text, len(text), true, 1)
+			}
+		case len(text) == 0 && register.linewise:
+			// the whole text was cut, so the lines become the text, without an empty line after them
+			text = []rune(strings.TrimSuffix(register.text, "\n"))
+			offset = firstNonBlankAt(nil, 0, register.text)
+		default:
+			if linewise && !register.linewise && len(text) > 0 {
+				text = insertText(text, start, "\n")
+			}
+			text, offset = v.put(text, start, true, 1)
+		}
+		v.register = replaced
+	default:
+		if motion, ok := v.motion(text, offset, cmd); ok {
+			offset = motion.target
+		}
+		return text, clampNormal(text, offset)
+	}
+	v.mode = vimNormal
+	return text, clampNormal(text, offset)
+}
+
+// the selected text, end is exclusive
+func (v vimEditor) selection(text []rune, offset int) (int, int) {
+	start, end := min(v.anchor, offset), max(v.anchor, offset)
+	if v.mode == vimVisualLine {
+		return wholeLines(text, start, end)
+	}
+	return start, min(end+1, len(text))
+}
+
+// where a motion moves the cursor to, and how an operator takes the text it moves over
+type vimMotion struct {
+	target    int
+	linewise  bool
+	inclusive bool
+}
+
+func (v *vimEditor) motion(text []rune, offset int, cmd vimCommand) (vimMotion, bool) {
+	n := cmd.count
+	switch cmd.key {
+	case "h", "left", "backspace":
+		return vimMotion{target: max(offset-n, lineStart(text, offset))}, true
+
+	case "l", "right", " ":
+		return vimMotion{target: min(offset+n, lineEnd(text, offset))}, true
+
+	case "j", "down", "k", "up":
+		line, col := lineCol(text, offset)
+		if cmd.key == "j" || cmd.key == "down" {
+			line += n
+		} else {
+			line -= n
+		}
+		line = max(min(line, strings.Count(string(text), "\n")), 0)
+		start := offsetOfLine(text, line)
+		return vimMotion{target: min(start+col, lineEnd(text, start)), linewise: true}, true
+
+	case "w", "W":
+		for i := 0; i < n; i++ {
+			offset = nextWordStart(text, offset, cmd.key == "W")
+		}
+		return vimMotion{target: offset}, true
+
+	case "b", "B":
+		for i := 0; i < n; i++ {
+			offset = prevWordStart(text, offset, cmd.key == "B")
+		}
+		return vimMotion{target: offset}, true
+
+	case "e", "E":
+		for i := 0; i < n; i++ {
+			offset = wordEnd(text, offset, cmd.key == "E")
+		}
+		return vimMotion{target: offset, inclusive: true}, true
+
+	case "0", "home":
+		return vimMotion{target: lineStart(text, offset)}, true
+
+	case "^":
+		return vimMotion{target: firstNonBlank(text, offset)}, true
+
+	case "$", "end":
+		line, _ := lineCol(text, offset)
+		return vimMotion{target: lineEnd(text, offsetOfLine(text, line+n-1))}, true
+
+	case "gg", "G":
+		line := strings.Count(string(text), "\n")
+		if cmd.counted || cmd.key == "gg" {
+			line = min(n-1, line)
+		}
+		return vimMotion{target: firstNonBlank(text, offsetOfLine(text, line)), linewise: true}, true
+
+	case "{", "}":
+		lines := strings.Split(string(text), "\n")
+		blank := func(l int) bool { return strings.TrimSpace(lines[l]) == "" }
+		line, _ := lineCol(text, offset)
+		for i := 0; i < n; i++ {
+			if cmd.key == "{" {
+				for line--; line > 0 && blank(line); line-- {
+				}
+				for ; line > 0 && !blank(line); line-- {
+				}
+			} else {
+				for line++; line < len(lines)-1 && blank(line); line++ {
+				}
+				for ; line < len(lines)-1 && !blank(line); line++ {
+				}
+			}
+		}
+		line = max(min(line, len(lines)-1), 0)
+		if cmd.key == "}" && line == len(lines)-1 && !blank(line) {
+			return vimMotion{target: len(text)}, true
+		}
+		return vimMotion{target: offsetOfLine(text, line)}, true
+
+	case "f", "t", "F", "T":
+		v.lastFind, v.lastFindChar = cmd.key, cmd.char
+		target, ok := findInLine(text, offset, cmd.key, cmd.char, n)
+		return vimMotion{target: target, inclusive: cmd.key == "f" || cmd.key == "t"}, ok
+
+	case ";", ",":
+		if v.lastFind == "" {
+			return vimMotion{}, false
+		}
+		find := v.lastFind
+		if cmd.key == "," {
+			find = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[find]
+		}
+		target, ok := findInLine(text, offset, find, v.lastFindChar, n)
+		if ok && target == offset && (find == "t" || find == "T") {
+			// repeating a t from just before the character would stay put, so it goes on to the next one
+			target, ok = findInLine(text, offset, find, v.lastFindChar, n+1)
+		}
+		return vimMotion{target: target, inclusive: find == "f" || find == "t"}, ok
+
+	case "%":
+		target, ok := matchBracket(text, offset)
+		return vimMotion{target: target, inclusive: true}, ok
+	}
+	return vimMotion{}, false
+}
+
+func (v *vimEditor) yank(text []rune, start int, end int, linewise bool) {
+	s := string(text[start:end])
+	if linewise && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	v.register = vimRegister{text: s, linewise: linewise}
+}
+
+// removes the text, putting it in the register
+func (v *vimEditor) cut(text []rune, start int, end int, linewise bool) []rune {
+	v.yank(text, start, end, linewise)
+	if linewise && end == len(text) && start > 0 && (end == start || text[end-1] != '\n') {
+		// the last line goes with the newline before it
+		start--
+	}
+	return removeText(text, start, end)
+}
+
+// puts the register after (or before) the cursor, or below (or above) the line when it's linewise
+func (v *vimEditor) put(text []rune, offset int, before bool, count int) ([]rune, int) {
+	s := strings.Repeat(v.register.text, count)
+	if s == "" {
+		return text, offset
+	}
+	if v.register.linewise {
+		at := lineStart(text, offset)
+		if !before {
+			at = lineEnd(text, offset)
+			if at == len(text) {
+				return insertText(text, at, "\n"+strings.TrimSuffix(s, "\n")), firstNonBlankAt(text, at, s)
+			}
+			at++
+		}
+		return insertText(text, at, s), firstNonBlankAt(text, at, s)
+	}
+	at := offset
+	if !before && offset < lineEnd(text, offset) {
+		at++
+	}
+	return insertText(text, at, s), at + len([]rune(s)) - 1
+}
+
+// the first non-blank character of lines put at offset, before they are inserted
+func firstNonBlankAt(text []rune, at int, s string) int {
+	if at == len(text) && at > 0 {
+		// put after the last line, following a newline
+		at++
+	}
+	return at + len([]rune(s)) - len([]rune(strings.TrimLeft(s, " \t")))
+}
+
+// the text and the cursor of the textarea, as an offset into the text
+func textOf(ta textarea.Model) ([]rune, int) {
+	value := ta.Value()
+	offset := 0
+	for i, l := range strings.Split(value, "\n") {
+		if i == ta.Line() {
+			break
+		}
+		offset += len([]rune(l)) + 1
+	}
+	info := ta.LineInfo()
+	return []rune(value), offset + info.StartColumn + info.ColumnOffset
+}
+
+// replaces the textarea's text, when it has changed, and moves the cursor to offset
+func setText(ta *textarea.Model, text []rune, offset int) {
+	if string(text) != ta.Value() {
+		ta.SetValue(string(text))
+		// the textarea may change the text as it's set, tabs become spaces
+		text = []rune(ta.Value())
+	}
+	line, col := lineCol(text, max(min(offset, len(text)), 0))
+	for ta.Line() < line {
+		ta.CursorDown()
+	}
+	for ta.Line() > line {
+		ta.CursorUp()
+	}
+	ta.SetCursor(col)
+}
+
+func insertText(text []rune, at int, s string) []rune {
+	result := append([]rune{}, text[:at]...)
+	result = append(result, []rune(s)...)
+	return append(result, text[at:]...)
+}
+
+func removeText(text []rune, start int, end int) []rune {
+	return append(append([]rune{}, text[:start]...), text[end:]...)
+}
+
+func toggleCase(text []rune, start int, end int) []rune {
+	text = append([]rune{}, text...)
+	for i := start; i < end; i++ {
+		if unicode.IsUpper(text[i]) {
+			text[i] = unicode.ToLower(text[i])
+		} else {
+			text[i] = unicode.ToUpper(text[i])
+		}
+	}
+	return text
+}
+
+// joins the line at offset with the next n lines, removing their indentation and separating them with a space
+func joinLines(text []rune, offset int, n int) ([]rune, int) {
+	for i := 0; i < n; i++ {
+		end := lineEnd(text, offset)
+		if end == len(text) {
+			break
+		}
+		next := end + 1
+		for next < len(text) && (text[next] == ' ' || text[next] == '\t') {
+			next++
+		}
+		sep := " "
+		if next == len(text) || text[next] == '\n' || text[next] == ')' || end == lineStart(text, end) || text[end-1] == ' ' {
+			sep = ""
+		}
+		text = append(append(append([]rune{}, text[:end]...), []rune(sep)...), text[next:]...)
+		offset = end
+	}
+	return text, offset
+}
+
+func lineStart(text []rune, offset int) int {
+	for offset > 0 && text[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
+
+func lineEnd(text []rune, offset int) int {
+	for offset < len(text) && text[offset] != '\n' {
+		offset++
+	}
+	return offset
+}
+
+func firstNonBlank(text []rune, offset int) int {
+	i, end := lineStart(text, offset), lineEnd(text, offset)
+	for i < end && (text[i] == ' ' || text[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// the line and column of offset
+func lineCol(text []rune, offset int) (int, int) {
+	line := 0
+	for _, r := range text[:offset] {
+		if r == '\n' {
+			line++
+		}
+	}
+	return line, offset - lineStart(text, offset)
+}
+
+// the offset of the start of line, or of the last line when there aren't that many
+func offsetOfLine(text []rune, line int) int {
+	offset := 0
+	for ; line > 0; line-- {
+		end := lineEnd(text, offset)
+		if end == len(text) {
+			break
+		}
+		offset = end + 1
+	}
+	return offset
+}
+
+// on a character rather than past the end of the line, unless the line is empty
+func clampNormal(text []rune, offset int) int {
+	offset = max(min(offset, len(text)), 0)
+	if offset == lineEnd(text, offset) && offset > lineStart(text, offset) {
+		return offset - 1
+	}
+	return offset
+}
+
+// the start and end of the lines from the line of start to the line of end, including the last newline
+func wholeLines(text []rune, start int, end int) (int, int) {
+	start = lineStart(text, start)
+	end = lineEnd(text, end)
+	if end < len(text) {
+		end++
+	}
+	return start, end
+}
+
+func lastIndex(text []rune, r rune) int {
+	for i := len(text) - 1; i >= 0; i-- {
+		if text[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// the class of a character for word motions, 0 for blanks, 1 for punctuation and 2 for word characters, or for
+// anything that isn't blank in a WORD
+func charClass(r rune, bigWord bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case bigWord || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 2
+	}
+	return 1
+}
+
+func nextWordStart(text []rune, i int, bigWord bool) int {
+	n := len(text)
+	if i >= n {
+		return n
+	}
+	if c := charClass(text[i], bigWord); c != 0 {
+		for i < n && charClass(text[i], bigWord) == c {
+			i++
+		}
+	}
+	for i < n && unicode.IsSpace(text[i]) {
+		// an empty line counts as a word
+		if text[i] == '\n' && i+1 < n && text[i+1] == '\n' {
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func prevWordStart(text []rune, i int, bigWord bool) int {
+	if i <= 0 {
+		return 0
+	}
+	i--
+	for i > 0 && unicode.IsSpace(text[i]) {
+		if text[i] == '\n' && text[i-1] == '\n' {
+			return i
+		}
+		i--
+	}
+	if unicode.IsSpace(text[i]) {
+		return i
+	}
+	c := charClass(text[i], bigWord)
+	for i > 0 && charClass(text[i-1], bigWord) == c {
+		i--
+	}
+	return i
+}
+
+func wordEnd(text []rune, i int, bigWord bool) int {
+	n := len(text)
+	i++
+	for i < n && unicode.IsSpace(text[i]) {
+		i++
+	}
+	if i >= n {
+		return max(n-1, 0)
+	}
+	c := charClass(text[i], bigWord)
+	for i+1 < n && charClass(text[i+1], bigWord) == c {
+		i++
+	}
+	return i
+}
+
+// the nth c after (f, t) or before (F, T) offset in its line, t and T stop next to it
+func findInLine(text []rune, offset int, find string, c rune, n int) (int, bool) {
+	start, end := lineStart(text, offset), lineEnd(text, offset)
+	forward := find == "f" || find == "t"
+	i := offset
+	for found := 0; found < n; {
+		if forward {
+			i++
+		} else {
+			i--
+		}
+		if i < start || i >= end {
+			return offset, false
+		}
+		if text[i] == c {
+			found++
+		}
+	}
+	switch find {
+	case "t":
+		i--
+	case "T":
+		i++
+	}
+	return i, true
+}
+
+// the bracket matching the first one at or after offset in its line
+func matchBracket(text []rune, offset int) (int, bool) {
+	const pairs = "()[]{}"
+	i, end := offset, lineEnd(text, offset)
+	for i < end && !strings.ContainsRune(pairs, text[i]) {
+		i++
+	}
+	if i >= end {
+		return offset, false
+	}
+	k := strings.IndexRune(pairs, text[i])
+	open, close := rune(pairs[k&^1]), rune(pairs[k|1])
+	step := 1
+	if text[i] == close {
+		step = -1
+	}
+	depth := 0
+	for j := i; j >= 0 && j < len(text); j += step {
+		switch text[j] {
+		case open:
+			depth += step
+		case close:
+			depth -= step
+		}
+		if depth == 0 {
+			return j, true
+		}
+	}
+	return offset, false
+}
+
+// the text of a text object, such as iw or a(, at offset, end is exclusive
+func textObject(text []rune, offset int, object string) (start int, end int, linewise bool, ok bool) {
+	inner := object[0] == 'i'
+	switch c := rune(object[1]); c {
+	case 'w', 'W':
+		start, end, ok = wordObject(text, offset, c == 'W', !inner)
+	case 'p':
+		start, end, ok = paragraphObject(text, offset, !inner)
+		linewise = true
+	case '"', '\'', '`':
+		start, end, ok = quoteObject(text, offset, c, inner)
+	default:
+		pairs := map[rune][2]rune{'(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'}, '{': {'{', '}'}, '}': {'{', '}'},
+			'B': {'{', '}'}, '[': {'[', ']'}, ']': {'[', ']'}, '<': {'<', '>'}, '>': {'<', '>'}}
+		pair := pairs[c]
+		start, end, ok = bracketObject(text, offset, pair[0], pair[1], inner)
+	}
+	return start, end, linewise, ok
+}
+
+func wordObject(text []rune, offset int, bigWord bool, around bool) (int, int, bool) {
+	if offset >= len(text) || text[offset] == '\n' {
+		return 0, 0, false
+	}
+	// words don't run on to the next line
+	class := func(i int) int {
+		if text[i] == '\n' {
+			return 3
+		}
+		return charClass(text[i], bigWord)
+	}
+	c := class(offset)
+	start, end := offset, offset+1
+	for start > 0 && class(start-1) == c {
+		start--
+	}
+	for end < len(text) && class(end) == c {
+		end++
+	}
+	if around {
+		if c != 0 {
+			// the blanks after the word, or before it when there aren't any after
+			blanksEnd := end
+			for blanksEnd < len(text) && class(blanksEnd) == 0 {
+				blanksEnd++
+			}
+			if blanksEnd > end {
+				end = blanksEnd
+			} else {
+				for start > 0 && class(start-1) == 0 {
+					start--
+				}
+			}
+		} else if end < len(text) && class(end) != 3 {
+			// the blanks and the word after them
+			next := class(end)
+			for end < len(text) && class(end) == next {
+				end++
+			}
+		}
+	}
+	return start, end, true
+}
+
+func paragraphObject(text []rune, offset int, around bool) (int, int, bool) {
+	lines := strings.Split(string(text), "\n")
+	blank := func(l int) bool { return strings.TrimSpace(lines[l]) == "" }
+	line, _ := lineCol(text, offset)
+	first, last := line, line
+	for first > 0 && blank(first-1) == blank(line) {
+		first--
+	}
+	for last < len(lines)-1 && blank(last+1) == blank(line) {
+		last++
+	}
+	if around {
+		// the blank lines after the paragraph
+		for last < len(lines)-1 && blank(last+1) != blank(line) {
+			last++
+		}
+	}
+	start, end := wholeLines(text, offsetOfLine(text, first), offsetOfLine(text, last))
+	return start, end, true
+}
+
+// the quoted text around offset in its line, or the next quoted text after it
+func quoteObject(text []rune, offset int, q rune, inner bool) (int, int, bool) {
+	var quotes []int
+	for i := lineStart(text, offset); i < lineEnd(text, offset); i++ {
+		if text[i] == q && (len(quotes) == 0 || text[i-1] != '\\') {
+			quotes = append(quotes, i)
+		}
+	}
+	for i := 0; i+1 < len(quotes); i += 2 {
+		if offset <= quotes[i+1] {
+			if inner {
+				return quotes[i] + 1, quotes[i+1], true
+			}
+			return quotes[i], quotes[i+1] + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// the text between the brackets around offset
+func bracketObject(text []rune, offset int, open rune, close rune, inner bool) (int, int, bool) {
+	start := -1
+	depth := 0
+	for i := min(offset, len(text)-1); i >= 0; i-- {
+		switch {
+		case text[i] == close && i != offset:
+			depth++
+		case text[i] == open:
+			if depth == 0 {
+				start = i
+			}
+			depth--
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	depth = 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+		if depth == 0 {
+			if inner {
+				return start + 1, i, true
+			}
+			return start, i + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// a part of the text drawn in a style over the textarea, end is exclusive
+type textHighlight struct {
+	start int
+	end   int
+	style lipgloss.Style
+}
+
+// the textarea's view with the highlighted text restyled, the rows without any highlights are left as they are
+func highlightTextarea(ta textarea.Model, highlights []textHighlight) string {
+	view := ta.View()
+	text, cursor := textOf(ta)
+	if len(highlights) == 0 || len(text) == 0 {
+		return view
+	}
+
+	var lines [][]rune
+	lineOffsets := []int{}
+	offset := 0
+	for _, l := range strings.Split(string(text), "\n") {
+		lines = append(lines, []rune(l))
+		lineOffsets = append(lineOffsets, offset)
+		offset += len([]rune(l)) + 1
+	}
+	rows := visualRows(ta)
+	first := scrollOffset(ta, rows, lines)
+	// the highlight the character at i is drawn in, the last one wins where they overlap, -1 for none
+	highlightAt := func(i int) int {
+		for j := len(highlights) - 1; j >= 0; j-- {
+			if i >= highlights[j].start && i < highlights[j].end {
+				return j
+			}
+		}
+		return -1
+	}
+
+	promptStyle := ta.BlurredStyle.Prompt
+	if ta.Focused() {
+		promptStyle = ta.FocusedStyle.Prompt
+	}
+	prompt := promptStyle.Inline(true).Render(ta.Prompt)
+
+	shown := strings.Split(view, "\n")
+	for i := range shown {
+		r := first + i
+		if r >= len(rows) {
+			break
+		}
+		row := rows[r]
+		start := lineOffsets[row.line] + row.start
+		end := lineOffsets[row.line] + len(lines[row.line])
+		lastRow := true
+		if r+1 < len(rows) && rows[r+1].line == row.line {
+			end = lineOffsets[row.line] + rows[r+1].start
+			lastRow = false
+		}
+		// the newline at the end of a line is drawn as a space
+		drawnEnd := end
+		if lastRow && end < len(text) {
+			drawnEnd++
+		}
+
+		highlighted := false
+		for o := start; o < drawnEnd && !highlighted; o++ {
+			highlighted = highlightAt(o) >= 0
+		}
+		if !highlighted {
+			continue
+		}
+
+		// the characters are drawn in runs of the same highlight, the cursor is a run of its own
+		var b strings.Builder
+		b.WriteString(prompt)
+		for o := start; o < drawnEnd; {
+			h := highlightAt(o)
+			st := lipgloss.NewStyle()
+			if h >= 0 {
+				st = highlights[h].style
+			}
+			runEnd := o + 1
+			if o == cursor && ta.Focused() {
+				st = st.Reverse(true)
+			} else {
+				for runEnd < drawnEnd && highlightAt(runEnd) == h && !(runEnd == cursor && ta.Focused()) {
+					runEnd++
+				}
+			}
+			run := string(text[o:min(runEnd, end)]) + strings.Repeat(" ", max(runEnd-max(o, end), 0))
+			b.WriteString(st.Render(run))
+			o = runEnd
+		}
+		if cursor == len(text) && drawnEnd == len(text) && ta.Focused() {
+			b.WriteString(lipgloss.NewStyle().Reverse(true).Render(" "))
+		}
+		shown[i] = b.String()
+	}
+	return strings.Join(shown, "\n")
+}
+
+// the visual selection drawn over the textarea
+func (v vimEditor) selectionHighlights(ta textarea.Model) []textHighlight {
+	if v.mode != vimVisual && v.mode != vimVisualLine {
+		return nil
+	}
+	text, offset := textOf(ta)
+	start, end := v.selection(text, offset)
+	return []textHighlight{{start: start, end: end, style: lipgloss.NewStyle().Background(colour.VimSelectionBG).Foreground(colour.VimSelectionFG)}}
+}
@@ -0,0 +1,252 @@
+package component
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// a query panel in vim mode with one buffer holding text, the | in text marks the cursor
+func newVimPanel(t *testing.T, text string) *QueryPanelModel {
+	t.Helper()
+	offset := strings.Index(text, "|")
+	if offset < 0 {
+		t.Fatalf("no cursor in %q", text)
+	}
+	m := NewQueryPanelModel("test")
+	m.SetEditorMode(EditorModeVim)
+	m.addBuffer("test.sql", strings.Replace(text, "|", "", 1))
+	m.SetSize(80, 30)
+	buffer := &m.buffers[0]
+	buffer.textarea.Focus()
+	runes, _ := textOf(buffer.textarea)
+	setText(&buffer.textarea, runes, len([]rune(text[:offset])))
+	return &m
+}
+
+// the keys typed by s, where <esc> is escape
+func vimTestKeys(s string) []tea.KeyMsg {
+	var keys []tea.KeyMsg
+	for s != "" {
+		if strings.HasPrefix(s, "<esc>") {
+			keys = append(keys, tea.KeyMsg{Type: tea.KeyEsc})
+			s = s[len("<esc>"):]
+			continue
+		}
+		r := []rune(s)[0]
+		keys = append(keys, runeKeys(string(r))...)
+		s = s[len(string(r)):]
+	}
+	return keys
+}
+
+// the buffer's text with a | at the cursor
+func vimPanelText(m *QueryPanelModel) string {
+	text, offset := textOf(m.buffers[0].textarea)
+	return string(text[:offset]) + "|" + string(text[offset:])
+}
+
+func typeVimKeys(m *QueryPanelModel, keys string) {
+	for _, k := range vimTestKeys(keys) {
+		m.vimKey(&m.buffers[0], k)
+	}
+}
+
+func TestVimMotions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		keys string
+		want string
+	}{
+		{"l", "|select 1", "l", "s|elect 1"},
+		{"l with a count", "|select 1", "3l", "sel|ect 1"},
+		{"l stops at the end of the line", "sele|ct", "9l", "selec|t"},
+		{"h", "sel|ect", "2h", "s|elect"},
+		{"w", "|select a, b", "w", "select |a, b"},
+		{"w stops at punctuation", "select |a, b", "w", "select a|, b"},
+		{"W skips punctuation", "select |a, b", "W", "select a, |b"},
+		{"b", "select a|, b", "b", "select |a, b"},
+		{"e", "|select a", "e", "selec|t a"},
+		{"0", "  sel|ect", "0", "|  select"},
+		{"^", "  sel|ect", "^", "  |select"},
+		{"$", "|select\nfrom", "$", "selec|t\nfrom"},
+		{"j keeps the column", "se|lect\nfrom t", "j", "select\nfr|om t"},
+		{"k", "select\nfr|om t", "k", "se|lect\nfrom t"},
+		{"gg", "select\nfrom\nwhe|re", "gg", "|select\nfrom\nwhere"},
+		{"G", "sel|ect\nfrom\nwhere", "G", "select\nfrom\n|where"},
+		{"f", "|select a, b", "f,", "select a|, b"},
+		{"t", "|select a, b", "t,", "select |a, b"},
+		{"F", "select a, |b", "Fe", "sel|ect a, b"},
+		{"; repeats f", "|a, b, c", "f,;", "a, b|, c"},
+		{", reverses f", "a, b, |c", "F,,", "a, b|, c"},
+		{"%", "|(a, (b))", "%", "(a, (b)|)"},
+		{"}", "|a\nb\n\nc", "}", "a\nb\n|\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newVimPanel(t, tt.text)
+			typeVimKeys(m, tt.keys)
+			if got := vimPanelText(m); got != tt.want {
+				t.Errorf("%q typing %q = %q, want %q", tt.text, tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVimOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		keys string
+		want string
+	}{
+		{"dw", "select |old new", "dw", "select |new"},
+		{"d with a count", "|a b c d", "d2w", "|c d"},
+		{"count before d", "|a b c d", "2dw", "|c d"},
+		{"dw at the end of a line keeps the newline", "select |a\nfrom t", "dw", "select| \nfrom t"},
+		{"de", "|select a", "de", "| a"},
+		{"d$", "sel|ect a", "d$", "se|l"},
+		{"dd", "a\n|b\nc", "dd", "a\n|c"},
+		{"dd with a count", "|a\nb\nc", "2dd", "|c"},
+		{"dj", "|a\nb\nc", "dj", "|c"},
+		{"dt", "|select a, b", "dt,", "|, b"},
+		{"df", "|select a, b", "df,", "| b"},
+		{"x", "|select", "3x", "|ect"},
+		{"X", "sel|ect", "X", "se|ect"},
+		{"D", "sel|ect", "D", "se|l"},
+		{"diw", "select na|me from t", "diw", "select | from t"},
+		{"daw", "select na|me from t", "daw", "select |from t"},
+		{"di(", "count(a|, b)", "di(", "count(|)"},
+		{"da(", "count(a|, b)", "da(", "coun|t"},
+		{"di\"", `where a = "x|yz"`, `di"`, `where a = "|"`},
+		{"cw changes to the end of the word", "select |old new", "cwfresh<esc>", "select fres|h new"},
+		{"cW changes to the end of the WORD", "select |a.b c", "cWx<esc>", "select |x c"},
+		{"cw on a space changes the space", "select| a", "cw_<esc>", "select|_a"},
+		{"cw on the last character of a word", "|a b", "cwx<esc>", "|x b"},
+		{"cw with a count", "|a b c", "2cwx<esc>", "|x c"},
+		{"ciw", "select na|me from t", "ciwid<esc>", "select i|d from t"},
+		{"cc keeps the line", "a\n|bc\nd", "ccx<esc>", "a\n|x\nd"},
+		{"yw then P", "|select a", "ywP", "select| select a"},
+		{"yy then p", "|a\nb", "yyp", "a\n|a\nb"},
+		{"dd then p puts the line below", "|a\nb", "ddp", "b\n|a"},
+		{"r", "|select", "2rX", "X|Xlect"},
+		{"~", "|select", "3~", "SEL|ect"},
+		{"J", "|a\nb", "J", "a| b"},
+		{"o", "|a\nb", "ox<esc>", "a\n|x\nb"},
+		{"u undoes the change", "|a b", "dwu", "|a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newVimPanel(t, tt.text)
+			typeVimKeys(m, tt.keys)
+			if got := vimPanelText(m); got != tt.want {
+				t.Errorf("%q typing %q = %q, want %q", tt.text, tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVimVisual(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		keys string
+		want string
+	}{
+		{"vd", "|select a", "vlld", "|ect a"},
+		{"v then a motion", "|select a", "ved", "| a"},
+		{"Vd", "a\n|b\nc", "Vd", "a\n|c"},
+		{"Vjd", "|a\nb\nc", "Vjd", "|c"},
+		{"viw", "select na|me", "viwd", "select| "},
+		{"vc", "|select a", "vec*<esc>", "|* a"},
+		{"v~", "|select", "vl~", "|SElect"},
+		{"o swaps the ends", "sel|ect", "vlohd", "se|t"},
+		{"esc leaves visual mode", "|select", "vl<esc>x", "s|lect"},
+		{"vp replaces the selection", "|select a", "yeWvp", "select selec|t"},
+		{"Vp over a middle line", "|a\nb\nc", "yyjVp", "a\n|a\nc"},
+		{"VP over the last line", "|a\nb", "yyjVP", "a\n|a"},
+		{"Vp over the last line with a newline before it", "|a\nb\nc", "yyGVp", "a\nb\n|a"},
+		{"Vp over the whole text", "  |a\nb", "yyVjp", "  |a"},
+		{"a charwise register into a line", "|x\nb\nc", "yljVp", "x\n|x\nc"},
+		{"a charwise register into the last line", "|x\nb", "yljVp", "x\n|x"},
+		{"a charwise register into the whole text", "|x\nb", "ylVjp", "|x"},
+		{"Vp puts the selection in the register", "|a\nb", "yyjVpP", "a\n|b\na"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newVimPanel(t, tt.text)
+			typeVimKeys(m, tt.keys)
+			if got := vimPanelText(m); got != tt.want {
+				t.Errorf("%q typing %q = %q, want %q", tt.text, tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVimRepeat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		keys string
+		want string
+	}{
+		{". repeats dw", "|a b c d", "dw.", "|c d"},
+		{". repeats x with its count", "|abcdef", "2x.", "|ef"},
+		{"a count replaces the change's count", "|abcdef", "2x3.", "|f"},
+		{"a count replaces the count after the operator", "|a b c d e", "d2w.", "|e"},
+		{"a count on a change without one", "|a b c d", "dw2.", "|d"},
+		{". repeats an insert", "|a", "ix<esc>.", "|xxa"},
+		{". repeats cw", "|a b", "cwx<esc>w.", "x |x"},
+		{". repeats a visual delete on as much text", "|abcdef", "vld.", "|ef"},
+		{". repeats a visual line delete on as many lines", "|a\nb\nc\nd\ne", "Vjd.", "|e"},
+		{". repeats a visual change", "|ab cd", "vlcx<esc>w.", "x |x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newVimPanel(t, tt.text)
+			typeVimKeys(m, tt.keys)
+			if got := vimPanelText(m); got != tt.want {
+				t.Errorf("%q typing %q = %q, want %q", tt.text, tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVimCommand(t *testing.T) {
+	tests := []struct {
+		keys     string
+		wantCmd  vimCommand
+		wantDone bool
+		wantOK   bool
+	}{
+		{"w", vimCommand{count: 1, key: "w"}, true, true},
+		{"3", vimCommand{count: 3, counted: true}, false, true},
+		{"3w", vimCommand{count: 3, counted: true, key: "w"}, true, true},
+		{"0", vimCommand{count: 1, key: "0"}, true, true},
+		{"10x", vimCommand{count: 10, counted: true, key: "x"}, true, true},
+		{"d", vimCommand{count: 1, operator: "d"}, false, true},
+		{"dd", vimCommand{count: 1, operator: "d", key: "dd"}, true, true},
+		{"2d3w", vimCommand{count: 6, counted: true, operator: "d", key: "w"}, true, true},
+		{"dg", vimCommand{count: 1, operator: "d"}, false, true},
+		{"dgg", vimCommand{count: 1, operator: "d", key: "gg"}, true, true},
+		{"diw", vimCommand{count: 1, operator: "d", key: "iw"}, true, true},
+		{"diz", vimCommand{count: 1, operator: "d", key: "iz"}, true, false},
+		{"fx", vimCommand{count: 1, key: "f", char: 'x'}, true, true},
+		{"dx", vimCommand{count: 1, operator: "d", key: "x"}, true, false},
+		{"z", vimCommand{count: 1, key: "z"}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			var keys []string
+			for _, k := range vimTestKeys(tt.keys) {
+				keys = append(keys, k.String())
+			}
+			cmd, done, ok := parseVimCommand(keys, false)
+			if cmd != tt.wantCmd || done != tt.wantDone || ok != tt.wantOK {
+				t.Errorf("parseVimCommand(%q) = %+v, %v, %v, want %+v, %v, %v", tt.keys, cmd, done, ok, tt.wantCmd, tt.wantDone, tt.wantOK)
+			}
+		})
+	}
+}
@@ -2,4 +2,8 @@ package constants
 
 const (
 	AppDesc = "A simple scratchpad for running ad-hoc database queries"
+
+	AutosaveIntervalConfigKey = "autosaveInterval"
+	AutosaveOnBlurConfigKey   = "autosaveOnBlur"
+	EditorModeConfigKey       = "editorMode"
 )
@@ -1,8 +1,37 @@
 package db
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+)
 
 type DBConn struct {
 	DB         *sql.DB
 	DriverName string
+	Host       string
+	Port       int
+	User       string
+	Database   string
+	// the session is opened read only and write statements are rejected
+	ReadOnly bool
+	// the classes of destructive statement that need confirming before they are run
+	ConfirmClasses []StatementClass
+	// the user's session, holding any open transaction
+	Session *Session
+}
+
+// opens the connection to the database configured for an alias
+type ConnectFunc func(alias string) (DBConn, error)
+
+// closes the session and the connection pool
+func (dbConn DBConn) Close() error {
+	if dbConn.Session != nil {
+		dbConn.Session.Close()
+	}
+	return dbConn.DB.Close()
+}
+
+// describes where the connection points, e.g. postgres://user@localhost:5432/music
+func (dbConn DBConn) Description() string {
+	return fmt.Sprintf("%s://%s@%s:%d/%s", dbConn.DriverName, dbConn.User, dbConn.Host, dbConn.Port, dbConn.Database)
 }
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type DiffStatus int
+
+const (
+	DiffUnchanged DiffStatus = iota
+	DiffAdded
+	DiffRemoved
+	DiffChanged
+)
+
+// a row matched by its key, From is nil for an added row and To is nil for a removed one
+type DiffRow struct {
+	Status  DiffStatus
+	Key     string
+	From    map[string]any
+	To      map[string]any
+	Changed map[string]bool
+}
+
+// the differences between two result sets, with the rows matched on a key column
+type ResultDiff struct {
+	Key string
+	// the columns of both results, those only in the second one last
+	Columns   []string
+	Rows      []DiffRow
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+}
+
+// a result set to compare, fetched for a command
+type CompareData struct {
+	Description string
+	Data        *Data
+}
+
+// matches the rows of the two results on the key column and finds those added, removed and changed
+func DiffResults(from, to *Data, key string) (*ResultDiff, error) {
+	if !slices.Contains(from.Columns, key) || !slices.Contains(to.Columns, key) {
+		return nil, fmt.Errorf("the key column '%s' has to be in both results", key)
+	}
+
+	diff := &ResultDiff{Key: key, Columns: slices.Clone(from.Columns)}
+	for _, c := range to.Columns {
+		if !slices.Contains(diff.Columns, c) {
+			diff.Columns = append(diff.Columns, c)
+		}
+	}
+
+	toRows, err := rowsByKey(to, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rowsByKey(from, key); err != nil {
+		return nil, err
+	}
+
+	matched := map[string]bool{}
+	for _, f := range from.Rows {
+		k := fmt.Sprintf("%v", f[key])
+		t, ok := toRows[k]
+		if !ok {
+			diff.Rows = append(diff.Rows, DiffRow{Status: DiffRemoved, Key: k, From: f})
+			diff.Removed++
+			continue
+		}
+		matched[k] = true
+
+		row := DiffRow{Status: DiffUnchanged, Key: k, From: f, To: t, Changed: map[string]bool{}}
+		for _, c := range diff.Columns {
+			fv, fok := f[c]
+			tv, tok := t[c]
+			if fok != tok || fmt.Sprintf("%v", fv) != fmt.Sprintf("%v", tv) {
+				row.Changed[c] = true
+				row.Status = DiffChanged
+			}
+		}
+		if row.Status == DiffChanged {
+			diff.Changed++
+		} else {
+			diff.Unchanged++
+		}
+		diff.Rows = append(diff.Rows, row)
+	}
+
+	for _, t := range to.Rows {
+		k := fmt.Sprintf("%v", t[key])
+		if !matched[k] {
+			diff.Rows = append(diff.Rows, DiffRow{Status: DiffAdded, Key: k, To: t})
+			diff.Added++
+		}
+	}
+	return diff, nil
+}
+
+// indexes the rows by their key value, which has to be unique
+func rowsByKey(data *Data, key string) (map[string]map[string]any, error) {
+	rows := map[string]map[string]any{}
+	for _, row := range data.Rows {
+		k := fmt.Sprintf("%v", row[key])
+		if _, ok := rows[k]; ok {
+			return nil, fmt.Errorf("the key column '%s' has more than one row with the value %s", key, k)
+		}
+		rows[k] = row
+	}
+	return rows, nil
+}
+
+// runs a query that reads data against another database, for comparing its results,
+// in a read only transaction that is always rolled back so nothing it does is kept
+func FetchForCompare(connect ConnectFunc, alias string, driverName string, query string, args ...any) (*Data, error) {
+	if !IsReadStatement(driverName, query) {
+		return nil, fmt.Errorf("only queries that read data can be run against another database")
+	}
+	dbConn, err := connect(alias)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close()
+
+	if len(args) > 0 && dbConn.DriverName != driverName {
+		return nil, fmt.Errorf("the query's parameters are bound for %s, so it can't be run against %s", driverName, dbConn.DriverName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getTimeoutSecs()*time.Second)
+	defer cancel()
+	var data *Data
+	start := time.Now()
+	err = dbConn.inReadOnlyTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		data, err = fetchRows(ctx, tx, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	data.Duration = time.Since(start)
+	return data, nil
+}
@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// an identifier, optionally quoted
+const identPattern = `(?:[A-Za-z_][A-Za-z0-9_$]*|"(?:[^"]|"")+"|` + "`(?:[^`]|``)+`" + `)`
+
+// a SELECT from a single table, optionally aliased, with nothing but a where, order or limit after it
+var sourceTableRe = regexp.MustCompile(`(?is)^\s*SELECT\s(.*?)\bFROM\s+(` + identPattern + `(?:\.` + identPattern + `)?)` +
+	`(?:\s+(?:AS\s+)?[A-Za-z_][A-Za-z0-9_]*)?\s*(?:(?:WHERE|ORDER|LIMIT|OFFSET|FETCH)\b.*)?;?\s*$`)
+
+// a table whose rows can be edited, because they can be identified by a primary key
+type EditableTable struct {
+	// the table as it was named in the query, e.g. public."Users"
+	Name       string
+	PrimaryKey []string
+	Columns    []TableColumn
+}
+
+type TableColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// the default value expression, empty when there isn't one
+	Default string
+}
+
+// a column and its value in a generated statement, a nil value is NULL
+type ColumnValue struct {
+	Column string
+	Value  any
+}
+
+// a generated statement, with bind parameters, and the same statement with the values
+// written in as literals so it can be reviewed
+type GeneratedStatement struct {
+	Query   string
+	Args    []any
+	Preview string
+}
+
+// an item of a select list that is a column of the table, or all of them, e.g. name, u.name, u.* or *
+var selectColumnRe = regexp.MustCompile(`^(?:` + identPattern + `\.){0,2}(?:` + identPattern + `|\*)$`)
+
+// returns the table a SELECT reads from, if it reads from a single table without joins,
+// grouping or subqueries, and selects only its columns (so each result row is a row of the table
+// and each result column one of its columns)
+func SourceTable(driverName string, query string) (string, bool) {
+	words, _ := sqlKeywords(driverName, query)
+	selects := 0
+	for _, w := range words {
+		switch w {
+		case "SELECT":
+			selects++
+		case "JOIN", "UNION", "INTERSECT", "EXCEPT", "GROUP", "HAVING", "DISTINCT":
+			return "", false
+		}
+	}
+	if selects != 1 {
+		return "", false
+	}
+
+	// literals and comments could contain anything, so leave them out, but keep quoted identifiers
+	var b strings.Builder
+	for _, seg := range splitSQL(driverName, query) {
+		switch {
+		case seg.code:
+			b.WriteString(seg.text)
+		case strings.HasPrefix(seg.text, `"`) || strings.HasPrefix(seg.text, "`"):
+			b.WriteString(seg.text)
+		case strings.HasPrefix(seg.text, "'") || strings.HasPrefix(seg.text, "$"):
+			b.WriteString("''")
+		default:
+			b.WriteString(" ")
+		}
+	}
+
+	m := sourceTableRe.FindStringSubmatch(b.String())
+	if m == nil {
+		return "", false
+	}
+	// function calls, expressions and aliases give result columns that aren't the table's
+	for _, item := range strings.Split(m[1], ",") {
+		if !selectColumnRe.MatchString(strings.TrimSpace(item)) {
+			return "", false
+		}
+	}
+	return m[2], true
+}
+
+var qualifiedTableRe = regexp.MustCompile(`^(` + identPattern + `)\.(` + identPattern + `)$`)
+
+// splits the table, as named in a query, into its schema (empty when it isn't qualified) and name,
+// without quotes as used in information_schema
+func splitTableName(driverName string, table string) (string, string) {
+	if m := qualifiedTableRe.FindStringSubmatch(table); m != nil {
+		return unquoteIdent(driverName, m[1]), unquoteIdent(driverName, m[2])
+	}
+	return "", unquoteIdent(driverName, table)
+}
+
+// the identifier as the database stores it, postgres folds unquoted identifiers to lower case
+func unquoteIdent(driverName string, name string) string {
+	if len(name) >= 2 && (name[0] == '"' || name[0] == '`') {
+		q := name[:1]
+		return strings.ReplaceAll(name[1:len(name)-1], q+q, q)
+	}
+	if driverName == DriverNamePostgres {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// looks up the primary key and columns of the table, failing if it has no primary key
+func GetEditableTable(dbConn DBConn, table string) (*EditableTable, error) {
+	schema, name := splitTableName(dbConn.DriverName, table)
+
+	indexes, err := GetTableIndexes(dbConn, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	editable := &EditableTable{Name: table}
+	for _, row := range indexes.Rows {
+		primary := fmt.Sprintf("%v", row["primary"])
+		if primary == "primary" || primary == "true" || primary == "t" {
+			for _, col := range strings.Split(fmt.Sprintf("%v", row["cols"]), ",") {
+				editable.PrimaryKey = append(editable.PrimaryKey, strings.TrimSpace(col))
+			}
+			break
+		}
+	}
+	if len(editable.PrimaryKey) == 0 {
+		return nil, fmt.Errorf("%s has no primary key, so its rows can't be edited", table)
+	}
+
+	columns, err := queryTableColumns(dbConn, schema, name, true)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, row := range columns.Rows {
+		col := TableColumn{
+			Name:     fmt.Sprintf("%v", row["name"]),
+			Type:     fmt.Sprintf("%v", row["type"]),
+			Nullable: row["nullable"] != "NOT NULL",
+		}
+		if def := fmt.Sprintf("%v", row["default"]); def != "NULL" {
+			col.Default = def
+		}
+		if !seen[col.Name] {
+			seen[col.Name] = true
+			editable.Columns = append(editable.Columns, col)
+		}
+	}
+	return editable, nil
+}
+
+func (t EditableTable) HasColumn(column string) bool {
+	for _, c := range t.Columns {
+		if c.Name == column {
+			return true
+		}
+	}
+	return false
+}
+
+func (t EditableTable) IsPrimaryKey(column string) bool {
+	for _, c := range t.PrimaryKey {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// builds the statement text and args together, so the placeholders match the driver
+type statementBuilder struct {
+	driverName string
+	query      strings.Builder
+	preview    strings.Builder
+	args       []any
+}
+
+func (b *statementBuilder) write(s string) {
+	b.query.WriteString(s)
+	b.preview.WriteString(s)
+}
+
+func (b *statementBuilder) ident(name string) {
+	b.write(quoteIdent(b.driverName, name))
+}
+
+func quoteIdent(driverName string, name string) string {
+	if driverName == DriverNameMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (b *statementBuilder) value(v any) {
+	if v == nil {
+		b.write("NULL")
+		return
+	}
+	b.args = append(b.args, v)
+	if b.driverName == DriverNamePostgres {
+		b.query.WriteString("$" + strconv.Itoa(len(b.args)))
+	} else {
+		b.query.WriteString("?")
+	}
+	b.preview.WriteString(previewLiteral(b.driverName, v))
+}
+
+// the value written as a string literal, for a preview of a statement that binds it
+func previewLiteral(driverName string, v any) string {
+	s := strings.ReplaceAll(fmt.Sprintf("%v", v), "'", "''")
+	if driverName == DriverNameMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + s + "'"
+}
+
+// writes `col = value` pairs separated by sep
+func (b *statementBuilder) assignments(values []ColumnValue, sep string) {
+	for i, cv := range values {
+		if i > 0 {
+			b.write(sep)
+		}
+		b.ident(cv.Column)
+		if cv.Value == nil && sep == " AND " {
+			b.write(" IS NULL")
+			continue
+		}
+		b.write(" = ")
+		b.value(cv.Value)
+	}
+}
+
+func (b *statementBuilder) statement() GeneratedStatement {
+	return GeneratedStatement{Query: b.query.String(), Args: b.args, Preview: b.preview.String()}
+}
+
+// generates an UPDATE of the row identified by the key values
+func BuildUpdate(driverName string, table string, set []ColumnValue, key []ColumnValue) GeneratedStatement {
+	b := &statementBuilder{driverName: driverName}
+	b.write("UPDATE " + table + " SET ")
+	b.assignments(set, ", ")
+	b.write(" WHERE ")
+	b.assignments(key, " AND ")
+	b.write(";")
+	return b.statement()
+}
+
+// generates an INSERT of the values, columns that aren't given get their default
+func BuildInsert(driverName string, table string, values []ColumnValue) GeneratedStatement {
+	b := &statementBuilder{driverName: driverName}
+	b.write("INSERT INTO " + table + " ")
+	if len(values) == 0 {
+		if driverName == DriverNameMySQL {
+			b.write("() VALUES ();")
+		} else {
+			b.write("DEFAULT VALUES;")
+		}
+		return b.statement()
+	}
+
+	b.write("(")
+	for i, cv := range values {
+		if i > 0 {
+			b.write(", ")
+		}
+		b.ident(cv.Column)
+	}
+	b.write(") VALUES (")
+	for i, cv := range values {
+		if i > 0 {
+			b.write(", ")
+		}
+		b.value(cv.Value)
+	}
+	b.write(");")
+	return b.statement()
+}
+
+// generates a DELETE of the row identified by the key values
+func BuildDelete(driverName string, table string, key []ColumnValue) GeneratedStatement {
+	b := &statementBuilder{driverName: driverName}
+	b.write("DELETE FROM " + table + " WHERE ")
+	b.assignments(key, " AND ")
+	b.write(";")
+	return b.statement()
+}
+
+// runs a generated statement that should change exactly one row, in the open transaction
+// or, if there isn't one, in a transaction of its own that is only committed if one row changed
+func ExecuteRowStatement(dbConn DBConn, query string, args ...any) error {
+	if dbConn.ReadOnly {
+		return ErrReadOnly
+	}
+
+	timeoutSecs := getTimeoutSecs()
+	queryCtx, cancel := context.WithTimeout(context.Background(), timeoutSecs*time.Second)
+	defer cancel()
+
+	return dbConn.inTransaction(queryCtx, func(tx *sql.Tx, own bool) error {
+		res, err := tx.ExecContext(queryCtx, query, args...)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n != 1 && own {
+			return fmt.Errorf("the statement would have changed %d rows instead of 1, so it has been rolled back", n)
+		}
+		if n != 1 {
+			return fmt.Errorf("the statement changed %d rows instead of 1, roll back the transaction to undo it", n)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package db
+
+import "testing"
+
+func TestSourceTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    string
+		query     string
+		wantTable string
+		wantOK    bool
+	}{
+		{"star", DriverNamePostgres, "select * from users", "users", true},
+		{"columns", DriverNamePostgres, "select id, name from users where id = 1", "users", true},
+		{"qualified table and alias", DriverNamePostgres, "SELECT u.id, u.* FROM public.users u ORDER BY id;", "public.users", true},
+		{"quoted identifiers", DriverNamePostgres, `select "Id", "Name" from "Users"`, `"Users"`, true},
+		{"mysql backticks", DriverNameMySQL, "select `id` from `users` limit 10", "`users`", true},
+		{"aggregate", DriverNamePostgres, "select count(*) from t", "", false},
+		{"expression", DriverNamePostgres, "select a+1 from t", "", false},
+		{"column alias", DriverNamePostgres, "select a as x from t", "", false},
+		{"column alias without as", DriverNamePostgres, "select a x from t", "", false},
+		{"computed column with an alias", DriverNamePostgres, "SELECT a+1 AS x FROM t", "", false},
+		{"literal", DriverNamePostgres, "select id, 'x' from t", "", false},
+		{"cast", DriverNamePostgres, "select id::text from t", "", false},
+		{"join", DriverNamePostgres, "select * from a join b on a.id = b.id", "", false},
+		{"group by", DriverNamePostgres, "select a from t group by a", "", false},
+		{"subquery", DriverNamePostgres, "select * from (select * from t) x", "", false},
+		{"not a select", DriverNamePostgres, "delete from t", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, ok := SourceTable(tt.driver, tt.query)
+			if table != tt.wantTable || ok != tt.wantOK {
+				t.Errorf("SourceTable(%q) = %q, %v, want %q, %v", tt.query, table, ok, tt.wantTable, tt.wantOK)
+			}
+		})
+	}
+}
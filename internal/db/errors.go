@@ -0,0 +1,95 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// mysql syntax errors quote the statement from where parsing failed, e.g. "... near 'FORM users' at line 1"
+var mysqlNearRe = regexp.MustCompile(`(?s)near '(.*)' at line (\d+)$`)
+
+// the parts of an error returned by the database
+type ErrorDetails struct {
+	Message string
+	// the error code, empty when the driver doesn't provide one
+	Code   string
+	Detail string
+	Hint   string
+}
+
+// the error as text, with the detail and hint on their own lines
+func (d ErrorDetails) String() string {
+	lines := []string{d.Message}
+	if d.Detail != "" {
+		lines = append(lines, "detail: "+d.Detail)
+	}
+	if d.Hint != "" {
+		lines = append(lines, "hint: "+d.Hint)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splits an error into its message, the error code and any detail or hint the database returned with it
+func DescribeError(err error) ErrorDetails {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return ErrorDetails{
+			Message: strings.Replace(err.Error(), pgErr.Error(), pgErr.Severity+": "+pgErr.Message, 1),
+			Code:    "SQLSTATE " + pgErr.Code,
+			Detail:  pgErr.Detail,
+			Hint:    pgErr.Hint,
+		}
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		code := fmt.Sprintf("MySQL error %d", myErr.Number)
+		if myErr.SQLState != [5]byte{} {
+			code += fmt.Sprintf(" (SQLSTATE %s)", myErr.SQLState[:])
+		}
+		return ErrorDetails{
+			Message: strings.Replace(err.Error(), myErr.Error(), myErr.Message, 1),
+			Code:    code,
+		}
+	}
+
+	return ErrorDetails{Message: err.Error()}
+}
+
+// returns the 1-based character position in query that the error refers to, or 0 when it doesn't say
+func ErrorPosition(err error, query string) int {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return int(pgErr.Position)
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		m := mysqlNearRe.FindStringSubmatch(myErr.Message)
+		if m == nil || m[1] == "" {
+			return 0
+		}
+		lineNumber, _ := strconv.Atoi(m[2])
+		lines := strings.SplitAfter(query, "\n")
+		if lineNumber < 1 || lineNumber > len(lines) {
+			return 0
+		}
+		// the quoted text runs on to the end of the statement, so only its first line is looked for
+		near := strings.SplitN(m[1], "\n", 2)[0]
+		i := strings.Index(lines[lineNumber-1], near)
+		if i < 0 {
+			return 0
+		}
+		before := strings.Join(lines[:lineNumber-1], "") + lines[lineNumber-1][:i]
+		return utf8.RuneCountInString(before) + 1
+	}
+
+	return 0
+}
@@ -0,0 +1,340 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a node in a query plan, times are in milliseconds
+type PlanNode struct {
+	Label      string
+	Details    []string
+	Cost       float64
+	Rows       float64
+	ActualTime float64
+	ActualRows float64
+	Loops      float64
+	Children   []*PlanNode
+}
+
+type Plan struct {
+	Root      *PlanNode
+	Analyzed  bool
+	Statement string
+}
+
+// the time (when analyzed) or cost of the node itself, excluding its children
+func (n *PlanNode) SelfWeight(analyzed bool) float64 {
+	weight := func(n *PlanNode) float64 {
+		if analyzed {
+			return n.ActualTime * max(n.Loops, 1)
+		}
+		return n.Cost
+	}
+	self := weight(n)
+	for _, c := range n.Children {
+		self -= weight(c)
+	}
+	return max(self, 0)
+}
+
+// returns the node with the highest self time (or cost when not analyzed)
+func (p *Plan) MostExpensiveNode() *PlanNode {
+	var (
+		most   *PlanNode
+		weight float64
+	)
+	var walk func(n *PlanNode)
+	walk = func(n *PlanNode) {
+		if w := n.SelfWeight(p.Analyzed); most == nil || w > weight {
+			most, weight = n, w
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	if p.Root != nil {
+		walk(p.Root)
+	}
+	return most
+}
+
+// runs the statement through the dialect's EXPLAIN (or EXPLAIN ANALYZE) and parses the plan
+func Explain(dbConn DBConn, query string, analyze bool, args ...any) (*Plan, error) {
+	if analyze && dbConn.ReadOnly && IsWriteStatement(dbConn.DriverName, query) {
+		return nil, ErrReadOnly
+	}
+
+	var explainQuery string
+	switch dbConn.DriverName {
+	case DriverNameMySQL:
+		// mysql only supports the tree format for EXPLAIN ANALYZE
+		if analyze {
+			explainQuery = "EXPLAIN ANALYZE " + query
+		} else {
+			explainQuery = "EXPLAIN FORMAT=JSON " + query
+		}
+	case DriverNamePostgres:
+		if analyze {
+			explainQuery = "EXPLAIN (ANALYZE, FORMAT JSON) " + query
+		} else {
+			explainQuery = "EXPLAIN (FORMAT JSON) " + query
+		}
+	}
+
+	timeoutSecs := getTimeoutSecs()
+	queryCtx, cancel := context.WithTimeout(context.Background(), timeoutSecs*time.Second)
+	defer cancel()
+
+	var data *Data
+	err := dbConn.withSession(queryCtx, func(runner queryRunner) error {
+		var err error
+		data, err = fetchRows(queryCtx, runner, explainQuery, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Rows) == 0 || len(data.Columns) == 0 {
+		return nil, fmt.Errorf("no query plan was returned")
+	}
+	output := fmt.Sprintf("%v", data.Rows[0][data.Columns[0]])
+
+	var root *PlanNode
+	switch {
+	case dbConn.DriverName == DriverNamePostgres:
+		root, err = parsePostgresPlan(output)
+	case analyze:
+		root, err = parseMySQLTreePlan(output)
+	default:
+		root, err = parseMySQLJSONPlan(output)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading query plan: %w", err)
+	}
+
+	return &Plan{Root: root, Analyzed: analyze, Statement: query}, nil
+}
+
+var postgresPlanDetailKeys = []string{
+	"Join Type", "Strategy", "Index Cond", "Recheck Cond", "Hash Cond", "Merge Cond",
+	"Join Filter", "Filter", "Rows Removed by Filter", "Sort Key", "Sort Method", "Group Key",
+}
+
+func parsePostgresPlan(output string) (*PlanNode, error) {
+	var plans []map[string]any
+	if err := json.Unmarshal([]byte(output), &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("empty plan")
+	}
+
+	plan, ok := plans[0]["Plan"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no plan found")
+	}
+	root := postgresPlanNode(plan)
+	for _, k := range []string{"Planning Time", "Execution Time"} {
+		if v, ok := plans[0][k]; ok {
+			root.Details = append(root.Details, fmt.Sprintf("%s: %v ms", k, v))
+		}
+	}
+	return root, nil
+}
+
+func postgresPlanNode(plan map[string]any) *PlanNode {
+	label := fmt.Sprintf("%v", plan["Node Type"])
+	if v, ok := plan["Index Name"]; ok {
+		label += fmt.Sprintf(" using %v", v)
+	}
+	if v, ok := plan["Relation Name"]; ok {
+		label += fmt.Sprintf(" on %v", v)
+		if alias, ok := plan["Alias"]; ok && alias != v {
+			label += fmt.Sprintf(" %v", alias)
+		}
+	}
+
+	node := &PlanNode{
+		Label:      label,
+		Cost:       toFloat(plan["Total Cost"]),
+		Rows:       toFloat(plan["Plan Rows"]),
+		ActualTime: toFloat(plan["Actual Total Time"]),
+		ActualRows: toFloat(plan["Actual Rows"]),
+		Loops:      toFloat(plan["Actual Loops"]),
+	}
+	for _, k := range postgresPlanDetailKeys {
+		if v, ok := plan[k]; ok {
+			node.Details = append(node.Details, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+
+	children, _ := plan["Plans"].([]any)
+	for _, c := range children {
+		if child, ok := c.(map[string]any); ok {
+			node.Children = append(node.Children, postgresPlanNode(child))
+		}
+	}
+	return node
+}
+
+var mysqlPlanDetailKeys = []string{
+	"access_type", "possible_keys", "key", "used_key_parts", "ref", "filtered", "attached_condition", "using_filesort", "using_temporary_table",
+}
+
+func parseMySQLJSONPlan(output string) (*PlanNode, error) {
+	var plan map[string]any
+	if err := json.Unmarshal([]byte(output), &plan); err != nil {
+		return nil, err
+	}
+	queryBlock, ok := plan["query_block"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no query block found")
+	}
+	return mysqlPlanNode("query_block", queryBlock), nil
+}
+
+func mysqlPlanNode(key string, plan map[string]any) *PlanNode {
+	label := strings.ReplaceAll(key, "_", " ")
+	if v, ok := plan["table_name"]; ok {
+		label = fmt.Sprintf("%s %v", label, v)
+		if access, ok := plan["access_type"]; ok {
+			label += fmt.Sprintf(" (%v)", access)
+		}
+	}
+
+	node := &PlanNode{Label: label}
+	if costInfo, ok := plan["cost_info"].(map[string]any); ok {
+		if v, ok := costInfo["query_cost"]; ok {
+			node.Cost = toFloat(v)
+		} else {
+			node.Cost = toFloat(costInfo["prefix_cost"])
+		}
+	}
+	if v, ok := plan["rows_produced_per_join"]; ok {
+		node.Rows = toFloat(v)
+	} else {
+		node.Rows = toFloat(plan["rows_examined_per_scan"])
+	}
+	for _, k := range mysqlPlanDetailKeys {
+		if v, ok := plan[k]; ok {
+			node.Details = append(node.Details, fmt.Sprintf("%s: %v", k, v))
+		}
+	}
+
+	keys := make([]string, 0, len(plan))
+	for k := range plan {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := plan[k].(type) {
+		case map[string]any:
+			if k != "cost_info" {
+				node.Children = append(node.Children, mysqlPlanNode(k, v))
+			}
+		case []any:
+			for _, item := range v {
+				child, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				// unwrap single key elements, e.g. nested_loop: [{"table": {...}}]
+				if len(child) == 1 {
+					for ck, cv := range child {
+						if inner, ok := cv.(map[string]any); ok {
+							node.Children = append(node.Children, mysqlPlanNode(ck, inner))
+							child = nil
+						}
+					}
+				}
+				if child != nil {
+					node.Children = append(node.Children, mysqlPlanNode(k, child))
+				}
+			}
+		}
+	}
+	return node
+}
+
+var (
+	mysqlTreeCostRe   = regexp.MustCompile(`\(cost=(?:[\d.e+]+\.\.)?([\d.e+]+) rows=([\d.e+]+)\)`)
+	mysqlTreeActualRe = regexp.MustCompile(`\(actual time=[\d.e+]+\.\.([\d.e+]+) rows=([\d.e+]+) loops=(\d+)\)`)
+)
+
+// parses the tree format of EXPLAIN ANALYZE, where each node is a line starting
+// with "->" and the indentation gives the nesting
+func parseMySQLTreePlan(output string) (*PlanNode, error) {
+	type level struct {
+		indent int
+		node   *PlanNode
+	}
+	var (
+		root  = &PlanNode{Label: "query"}
+		stack = []level{{indent: -1, node: root}}
+		last  *PlanNode
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "->") {
+			// a continuation of the previous node's text
+			if last != nil && strings.TrimSpace(line) != "" {
+				last.Details = append(last.Details, strings.TrimSpace(line))
+			}
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		text := strings.TrimSpace(strings.TrimPrefix(trimmed, "->"))
+
+		node := &PlanNode{Label: text}
+		if i := strings.Index(text, "  (cost="); i >= 0 {
+			node.Label = text[:i]
+		} else if i := strings.Index(text, "  (actual time="); i >= 0 {
+			node.Label = text[:i]
+		}
+		if m := mysqlTreeCostRe.FindStringSubmatch(text); m != nil {
+			node.Cost = toFloat(m[1])
+			node.Rows = toFloat(m[2])
+		}
+		if m := mysqlTreeActualRe.FindStringSubmatch(text); m != nil {
+			node.ActualTime = toFloat(m[1])
+			node.ActualRows = toFloat(m[2])
+			node.Loops = toFloat(m[3])
+		}
+
+		for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, level{indent: indent, node: node})
+		last = node
+	}
+
+	if len(root.Children) == 0 {
+		return nil, fmt.Errorf("no plan nodes found")
+	}
+	if len(root.Children) == 1 {
+		return root.Children[0], nil
+	}
+	return root, nil
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// the kinds of destructive statement that can be set to need confirmation before running
+type StatementClass string
+
+const (
+	// UPDATE or DELETE without a WHERE clause
+	StatementClassUnfiltered StatementClass = "unfiltered"
+	StatementClassDrop       StatementClass = "drop"
+	StatementClassTruncate   StatementClass = "truncate"
+	StatementClassAlter      StatementClass = "alter"
+)
+
+// the classes that need confirmation when a connection doesn't configure them
+var DefaultConfirmClasses = []StatementClass{
+	StatementClassUnfiltered,
+	StatementClassDrop,
+	StatementClassTruncate,
+	StatementClassAlter,
+}
+
+func (c StatementClass) Description() string {
+	switch c {
+	case StatementClassUnfiltered:
+		return "this statement has no WHERE clause and will change every row"
+	case StatementClassDrop:
+		return "this statement drops an object"
+	case StatementClassTruncate:
+		return "this statement removes every row"
+	case StatementClassAlter:
+		return "this statement alters an object"
+	}
+	return ""
+}
+
+// returns the class with the given name, as set in a connection's confirmStatements
+func ParseStatementClass(name string) (StatementClass, error) {
+	names := make([]string, len(DefaultConfirmClasses))
+	for i, c := range DefaultConfirmClasses {
+		if strings.EqualFold(name, string(c)) {
+			return c, nil
+		}
+		names[i] = string(c)
+	}
+	return "", fmt.Errorf("unknown statement class '%s', valid classes are: %s", name, strings.Join(names, ", "))
+}
+
+var sqlTokenRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*|[()]`)
+
+// returns the words of the statement (outside of literals and comments) upper cased,
+// along with their parenthesis depth
+func sqlKeywords(driverName string, query string) ([]string, []int) {
+	var b strings.Builder
+	for _, seg := range splitSQL(driverName, query) {
+		if seg.code {
+			b.WriteString(seg.text)
+		} else {
+			b.WriteString(" ")
+		}
+	}
+
+	var (
+		words  []string
+		depths []int
+		depth  int
+	)
+	for _, tok := range sqlTokenRe.FindAllString(b.String(), -1) {
+		switch tok {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		default:
+			words = append(words, strings.ToUpper(tok))
+			depths = append(depths, depth)
+		}
+	}
+	return words, depths
+}
+
+// the words of an EXPLAIN that can come before the statement
+var explainOptionWords = map[string]bool{
+	"ANALYZE": true, "ANALYSE": true, "VERBOSE": true, "FORMAT": true, "TREE": true, "JSON": true, "TRADITIONAL": true,
+}
+
+// the statement an EXPLAIN ANALYZE runs, as the words and depths of the statement with the EXPLAIN removed,
+// a plain EXPLAIN doesn't run its statement so it has no words
+func skipExplain(words []string, depths []int) ([]string, []int) {
+	if len(words) == 0 || words[0] != "EXPLAIN" {
+		return words, depths
+	}
+	analyze := false
+	for i := 1; i < len(words); i++ {
+		switch {
+		case words[i] == "ANALYZE" || words[i] == "ANALYSE":
+			// as a word or in the parenthesised options, such as EXPLAIN (ANALYZE, BUFFERS)
+			analyze = true
+		case depths[i] > 0 || explainOptionWords[words[i]]:
+		default:
+			if analyze {
+				return words[i:], depths[i:]
+			}
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// works out whether the statement is destructive, returning its class if so,
+// EXPLAIN ANALYZE is classed by the statement it runs
+func ClassifyStatement(driverName string, query string) (StatementClass, bool) {
+	words, depths := skipExplain(sqlKeywords(driverName, query))
+	if len(words) == 0 {
+		return "", false
+	}
+
+	// skip over any common table expressions to the main statement
+	start := 0
+	if words[0] == "WITH" {
+		start = -1
+		for i := 1; i < len(words); i++ {
+			if depths[i] == 0 && (words[i] == "UPDATE" || words[i] == "DELETE" || words[i] == "SELECT" || words[i] == "INSERT") {
+				start = i
+				break
+			}
+		}
+		if start == -1 {
+			return "", false
+		}
+	}
+
+	switch words[start] {
+	case "UPDATE", "DELETE":
+		for i := start + 1; i < len(words); i++ {
+			if depths[i] == depths[start] && words[i] == "WHERE" {
+				return "", false
+			}
+		}
+		return StatementClassUnfiltered, true
+	case "DROP":
+		return StatementClassDrop, true
+	case "TRUNCATE":
+		return StatementClassTruncate, true
+	case "ALTER":
+		return StatementClassAlter, true
+	}
+	return "", false
+}
+
+// the statements that modify data or schema
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true, "UPSERT": true,
+	"DROP": true, "TRUNCATE": true, "CREATE": true, "ALTER": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "COMMENT": true, "LOAD": true,
+	"VACUUM": true, "REINDEX": true, "CLUSTER": true, "REFRESH": true,
+	// procedures and anonymous code blocks can do anything
+	"CALL": true, "DO": true,
+}
+
+// the words of a SET or RESET that make (or stop) the session being read only,
+// RESET ALL and setting the session's transaction characteristics included
+var readOnlySettingWords = map[string]bool{
+	"DEFAULT_TRANSACTION_READ_ONLY": true, "TRANSACTION_READ_ONLY": true, "TX_READ_ONLY": true,
+	"TRANSACTION": true, "CHARACTERISTICS": true, "ALL": true,
+}
+
+// reports whether the statement modifies data or schema, or changes whether the session is read only,
+// a WITH statement counts when its main statement or any of its common table expressions is a write,
+// and EXPLAIN ANALYZE when the statement it runs is a write
+func IsWriteStatement(driverName string, query string) bool {
+	words, depths := skipExplain(sqlKeywords(driverName, query))
+	if len(words) == 0 {
+		return false
+	}
+
+	main := 0
+	if words[0] == "WITH" {
+		main = -1
+		for i := 1; i < len(words) && main == -1; i++ {
+			switch {
+			case depths[i] > depths[i-1]:
+				// the first word inside a parenthesis, which starts the body of a common table expression
+				if writeKeywords[words[i]] {
+					return true
+				}
+			case depths[i] == 0 && (words[i] == "SELECT" || writeKeywords[words[i]]):
+				main = i
+			}
+		}
+		if main == -1 {
+			return false
+		}
+	}
+
+	// whether word appears in the main statement, outside of any parentheses
+	has := func(word string) bool {
+		for i := main + 1; i < len(words); i++ {
+			if words[i] == word && depths[i] == depths[main] {
+				return true
+			}
+		}
+		return false
+	}
+	switch words[main] {
+	case "SELECT":
+		// SELECT ... INTO creates a table in postgres, and writes a file (or sets variables) in mysql
+		return has("INTO")
+	case "COPY":
+		// COPY ... FROM loads rows into the table, where COPY ... TO only reads them
+		return has("FROM")
+	case "SET", "RESET":
+		for _, w := range words[main+1:] {
+			if readOnlySettingWords[w] {
+				return true
+			}
+		}
+		return false
+	}
+	return writeKeywords[words[main]]
+}
+
+// reports whether the statement only reads data, a SELECT (including one with common table expressions
+// that don't write), VALUES, TABLE or SHOW
+func IsReadStatement(driverName string, query string) bool {
+	words, _ := sqlKeywords(driverName, query)
+	if len(words) == 0 || IsWriteStatement(driverName, query) {
+		return false
+	}
+	switch words[0] {
+	case "SELECT", "WITH", "VALUES", "TABLE", "SHOW":
+		return true
+	}
+	return false
+}
+
+// returns the class of the statement if it is one that the connection is configured to confirm
+func (dbConn DBConn) NeedsConfirmation(query string) (StatementClass, bool) {
+	class, ok := ClassifyStatement(dbConn.DriverName, query)
+	if !ok {
+		return "", false
+	}
+	for _, c := range dbConn.ConfirmClasses {
+		if c == class {
+			return class, true
+		}
+	}
+	return "", false
+}
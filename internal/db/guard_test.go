@@ -0,0 +1,93 @@
+package db
+
+import "testing"
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		query  string
+		want   bool
+	}{
+		{"select", DriverNamePostgres, "select * from t", false},
+		{"empty", DriverNamePostgres, "", false},
+		{"insert", DriverNamePostgres, "insert into t values (1)", true},
+		{"lower case update", DriverNameMySQL, "update t set a = 1", true},
+		{"leading line comment", DriverNamePostgres, "-- tidy up\ndelete from t", true},
+		{"leading block comment", DriverNamePostgres, "/* tidy up */ delete from t", true},
+		{"keyword in a string", DriverNamePostgres, "select 'delete from t'", false},
+		{"merge", DriverNamePostgres, "merge into t using s on t.id = s.id when matched then delete", true},
+		{"replace", DriverNameMySQL, "replace into t values (1)", true},
+		{"with a write in a cte", DriverNamePostgres, "with d as (delete from t returning *) select * from d", true},
+		{"with a write as the main statement", DriverNamePostgres, "with x as (select 1) delete from t", true},
+		{"with a select", DriverNamePostgres, "with x as (select 1) select * from x", false},
+		{"with a select for update", DriverNamePostgres, "with x as (select 1) select * from x for update", false},
+		{"with column names", DriverNamePostgres, "with x (a) as (select 1) insert into t select a from x", true},
+		{"call", DriverNamePostgres, "call p()", true},
+		{"do block", DriverNamePostgres, "do $$ begin delete from t; end $$", true},
+		{"select into a new table", DriverNamePostgres, "select * into newt from t", true},
+		{"select from a subquery", DriverNamePostgres, "select * from (select 1) x", false},
+		{"copy from", DriverNamePostgres, "copy t from '/tmp/t.csv'", true},
+		{"copy to", DriverNamePostgres, "copy (select * from t) to stdout", false},
+		{"set read only off", DriverNamePostgres, "SET default_transaction_read_only = off", true},
+		{"set session read only off", DriverNamePostgres, "set session default_transaction_read_only to off", true},
+		{"set transaction read write", DriverNamePostgres, "set transaction read write", true},
+		{"set session characteristics", DriverNamePostgres, "set session characteristics as transaction read write", true},
+		{"reset read only", DriverNamePostgres, "reset default_transaction_read_only", true},
+		{"reset all", DriverNamePostgres, "reset all", true},
+		{"mysql set session transaction", DriverNameMySQL, "SET SESSION TRANSACTION READ WRITE", true},
+		{"mysql set transaction_read_only", DriverNameMySQL, "set @@session.transaction_read_only = 0", true},
+		{"set search_path", DriverNamePostgres, "set search_path to music, public", false},
+		{"set a time zone", DriverNameMySQL, "set time_zone = 'transaction'", false},
+		{"explain analyze a write", DriverNamePostgres, "explain analyze delete from t", true},
+		{"explain with analyze in the options", DriverNamePostgres, "explain (analyze, format json) insert into t values (1)", true},
+		{"explain analyze a select", DriverNamePostgres, "explain analyze select * from t", false},
+		{"explain without analyze", DriverNamePostgres, "explain delete from t", false},
+		{"mysql explain analyze", DriverNameMySQL, "EXPLAIN ANALYZE FORMAT=TREE update t set a = 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWriteStatement(tt.driver, tt.query); got != tt.want {
+				t.Errorf("IsWriteStatement(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyStatement(t *testing.T) {
+	tests := []struct {
+		name      string
+		driver    string
+		query     string
+		wantClass StatementClass
+		wantOK    bool
+	}{
+		{"select", DriverNamePostgres, "select * from t", "", false},
+		{"update without where", DriverNamePostgres, "update t set a = 1", StatementClassUnfiltered, true},
+		{"update with where", DriverNamePostgres, "update t set a = 1 where id = 2", "", false},
+		{"delete without where", DriverNameMySQL, "DELETE FROM t", StatementClassUnfiltered, true},
+		{"delete with where", DriverNamePostgres, "delete from t where id = 2", "", false},
+		{"where in a subquery only", DriverNamePostgres, "delete from t using (select id from s where a) x", StatementClassUnfiltered, true},
+		{"where in a comment", DriverNamePostgres, "delete from t -- where id = 2", StatementClassUnfiltered, true},
+		{"where in a string", DriverNamePostgres, "update t set a = 'where'", StatementClassUnfiltered, true},
+		{"with delete", DriverNamePostgres, "with x as (select 1) delete from t", StatementClassUnfiltered, true},
+		{"drop", DriverNamePostgres, "drop table t", StatementClassDrop, true},
+		{"truncate", DriverNamePostgres, "/* reset */ truncate t", StatementClassTruncate, true},
+		{"alter", DriverNameMySQL, "alter table t add column a int", StatementClassAlter, true},
+		{"insert", DriverNamePostgres, "insert into t values (1)", "", false},
+		{"explain analyze", DriverNamePostgres, "EXPLAIN ANALYZE DELETE FROM orders", StatementClassUnfiltered, true},
+		{"explain with analyze in the options", DriverNamePostgres, "explain (analyze, format json) delete from orders", StatementClassUnfiltered, true},
+		{"explain analyze verbose", DriverNamePostgres, "explain analyse verbose drop table t", StatementClassDrop, true},
+		{"explain analyze with where", DriverNamePostgres, "explain analyze delete from orders where id = 1", "", false},
+		{"explain without analyze", DriverNamePostgres, "explain delete from orders", "", false},
+		{"explain with analyze off", DriverNamePostgres, "explain (costs off) delete from orders", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, ok := ClassifyStatement(tt.driver, tt.query)
+			if class != tt.wantClass || ok != tt.wantOK {
+				t.Errorf("ClassifyStatement(%q) = %q, %v, want %q, %v", tt.query, class, ok, tt.wantClass, tt.wantOK)
+			}
+		})
+	}
+}
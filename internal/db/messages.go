@@ -1,10 +1,28 @@
 package db
 
+import "time"
+
 type Data struct {
 	Columns []string
 	Rows    []map[string]any
+	// the table the rows were selected from, when they all come from one table
+	Table string
+	// the query for a page of table data, nil for other results
+	TableQuery *TableQuery
+	// there are more rows in the table after this page
+	HasMore bool
+	// the query (and its bind parameter args) the rows were fetched with
+	Query string
+	Args  []any
+	// how long the statement took, and the rows it changed when it doesn't return rows
+	Duration     time.Duration
+	RowsAffected int64
 }
 
 type DataMsg *Data
 type TableInfoDataMsg *Data
 type SchemaTablesMsg *Data
+type ExplainMsg *Plan
+type EditableTableMsg *EditableTable
+type ColumnStatsMsg *ColumnStats
+type CompareDataMsg *CompareData
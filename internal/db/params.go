@@ -0,0 +1,295 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// a piece of sql text, code is false for string literals, quoted identifiers and comments
+type sqlSegment struct {
+	text string
+	code bool
+}
+
+var (
+	dollarQuoteTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+	setVariableRe  = regexp.MustCompile(`^\s*--\s*@set\s+([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*?)\s*;?\s*$`)
+)
+
+// splits sql text into code and non-code (literals, quoted identifiers, comments) segments,
+// a backslash escapes the next character of a string in mysql, but in postgres only in an E'...' string
+func splitSQL(driverName string, query string) []sqlSegment {
+	var (
+		segments []sqlSegment
+		start    int
+	)
+
+	flush := func(end int, code bool) {
+		if end > start {
+			segments = append(segments, sqlSegment{text: query[start:end], code: code})
+		}
+		start = end
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			backslashEscapes := c == '\'' && (driverName == DriverNameMySQL || isEscapeStringPrefix(query, i))
+			flush(i, true)
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					// a doubled quote is an escaped quote
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					end++
+					break
+				}
+				if query[end] == '\\' && backslashEscapes {
+					end++
+				}
+				end++
+			}
+			i = min(end, len(query))
+			flush(i, false)
+
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			flush(i, true)
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				i = len(query)
+			} else {
+				i += end
+			}
+			flush(i, false)
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			flush(i, true)
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+			flush(i, false)
+
+		case c == '$' && dollarQuoteTag.MatchString(query[i:]):
+			flush(i, true)
+			tag := dollarQuoteTag.FindString(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end == -1 {
+				i = len(query)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			flush(i, false)
+
+		default:
+			i++
+		}
+	}
+	flush(len(query), true)
+
+	return segments
+}
+
+// reports whether the quote at i starts a postgres E'...' string, where a backslash is an escape
+func isEscapeStringPrefix(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	// the E can't be the end of a longer word, such as the name of a column
+	return i == 1 || !isParamNameChar(query[i-2])
+}
+
+func isParamNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isParamNameChar(c byte) bool {
+	return isParamNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// finds the placeholder at the start of s (either :name or ${name}), returning the name and length,
+// within square brackets a : is an array slice (a[lo:hi]) rather than a placeholder
+func matchParam(s string, prev byte, inBrackets bool) (string, int) {
+	if strings.HasPrefix(s, "${") {
+		end := strings.IndexByte(s, '}')
+		if end > 2 && isParamNameStart(s[2]) {
+			name := s[2:end]
+			for i := range name {
+				if !isParamNameChar(name[i]) {
+					return "", 0
+				}
+			}
+			return name, end + 1
+		}
+		return "", 0
+	}
+
+	// skip postgres casts (::int) and mysql assignments (:=)
+	if s[0] != ':' || prev == ':' || inBrackets || len(s) < 2 || !isParamNameStart(s[1]) {
+		return "", 0
+	}
+	end := 2
+	for end < len(s) && isParamNameChar(s[end]) {
+		end++
+	}
+	return s[1:end], end
+}
+
+// replaces every placeholder in the query with the result of fn
+func replaceParams(driverName string, query string, fn func(name string) string) string {
+	var (
+		b        strings.Builder
+		brackets int
+	)
+	for _, seg := range splitSQL(driverName, query) {
+		if !seg.code {
+			b.WriteString(seg.text)
+			continue
+		}
+		var prev byte
+		for i := 0; i < len(seg.text); {
+			if name, n := matchParam(seg.text[i:], prev, brackets > 0); n > 0 {
+				b.WriteString(fn(name))
+				prev = seg.text[i+n-1]
+				i += n
+				continue
+			}
+			prev = seg.text[i]
+			brackets = bracketDepth(brackets, prev)
+			b.WriteByte(prev)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// the square bracket depth after c
+func bracketDepth(depth int, c byte) int {
+	switch c {
+	case '[':
+		return depth + 1
+	case ']':
+		return max(depth-1, 0)
+	}
+	return depth
+}
+
+// returns the distinct placeholder names (:name or ${name}) used in the query, in order of appearance
+func QueryParams(driverName string, query string) []string {
+	var names []string
+	seen := map[string]bool{}
+	replaceParams(driverName, query, func(name string) string {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return ""
+	})
+	return names
+}
+
+// rewrites the placeholders in the query as driver bind parameters and returns the matching args
+func BindParams(driverName string, query string, values map[string]string) (string, []any) {
+	var args []any
+	bound := replaceParams(driverName, query, func(name string) string {
+		args = append(args, values[name])
+		if driverName == DriverNamePostgres {
+			return "$" + strconv.Itoa(len(args))
+		}
+		return "?"
+	})
+	return bound, args
+}
+
+// the query with its placeholders replaced by their values written as literals, to show what will run
+func PreviewParams(driverName string, query string, values map[string]string) string {
+	return replaceParams(driverName, query, func(name string) string {
+		return previewLiteral(driverName, values[name])
+	})
+}
+
+// maps a 1-based character position in the query returned by BindParams back to a 0-based
+// character offset in the original query, a position within a bind parameter maps to its placeholder
+func UnbindPosition(driverName string, query string, position int) int {
+	target := position - 1
+	// characters seen so far in the bound and the original query
+	bound, original, args, brackets := 0, 0, 0, 0
+	for _, seg := range splitSQL(driverName, query) {
+		if !seg.code {
+			n := utf8.RuneCountInString(seg.text)
+			if target < bound+n {
+				return original + target - bound
+			}
+			bound += n
+			original += n
+			continue
+		}
+		var prev byte
+		for i := 0; i < len(seg.text); {
+			if _, n := matchParam(seg.text[i:], prev, brackets > 0); n > 0 {
+				args++
+				param := "?"
+				if driverName == DriverNamePostgres {
+					param = "$" + strconv.Itoa(args)
+				}
+				if target < bound+len(param) {
+					return original
+				}
+				bound += len(param)
+				original += n
+				prev = seg.text[i+n-1]
+				i += n
+				continue
+			}
+			prev = seg.text[i]
+			brackets = bracketDepth(brackets, prev)
+			if utf8.RuneStart(prev) {
+				if target == bound {
+					return original
+				}
+				bound++
+				original++
+			}
+			i++
+		}
+	}
+	return original + target - bound
+}
+
+// reads the `-- @set name = value` variable definitions from the query buffer
+func ParseSetVariables(text string) map[string]string {
+	vars := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		if m := setVariableRe.FindStringSubmatch(line); m != nil {
+			vars[m[1]] = unquote(m[2])
+		}
+	}
+	return vars
+}
+
+// removes the `-- @set` lines from a statement so they are not sent to the database
+func RemoveSetVariables(query string) string {
+	lines := strings.Split(query, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !setVariableRe.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
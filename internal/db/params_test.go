@@ -0,0 +1,134 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		query  string
+		want   []string
+	}{
+		{"colon params", DriverNamePostgres, "select * from t where a = :a and b = :b", []string{"a", "b"}},
+		{"brace params", DriverNameMySQL, "select * from t where a = ${a}", []string{"a"}},
+		{"repeated params are listed once", DriverNamePostgres, "select :a, :b, :a", []string{"a", "b"}},
+		{"casts", DriverNamePostgres, "select :a::int, b::text from t", []string{"a"}},
+		{"mysql assignment", DriverNameMySQL, "set @x := :a", []string{"a"}},
+		{"single quoted strings", DriverNamePostgres, "select ':a', :b", []string{"b"}},
+		{"doubled quotes", DriverNamePostgres, "select 'it''s :a', :b", []string{"b"}},
+		{"double quoted identifiers", DriverNamePostgres, `select ":a" from t where b = :b`, []string{"b"}},
+		{"backticks", DriverNameMySQL, "select `:a` from t where b = :b", []string{"b"}},
+		{"line comments", DriverNamePostgres, "select :a -- and :b\nfrom t", []string{"a"}},
+		{"block comments", DriverNamePostgres, "select /* :a */ :b", []string{"b"}},
+		{"dollar quoting", DriverNamePostgres, "select $$ :a $$, $tag$ :b $tag$, :c", []string{"c"}},
+		{"postgres backslash isn't an escape", DriverNamePostgres, `select '\', :id`, []string{"id"}},
+		{"postgres E string backslash escapes", DriverNamePostgres, `select E'\' :a', :b`, []string{"b"}},
+		{"a word ending in e before a string", DriverNamePostgres, `select date'\', :id`, []string{"id"}},
+		{"mysql backslash escapes", DriverNameMySQL, `select '\' :a', :b`, []string{"b"}},
+		{"array slices", DriverNamePostgres, "select a[lo:hi], a[1:2] from t where b = :b", []string{"b"}},
+		{"brace params in brackets", DriverNamePostgres, "select a[${i}]", []string{"i"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QueryParams(tt.driver, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryParams(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	values := map[string]string{"a": "1", "b": "2"}
+	tests := []struct {
+		name      string
+		driver    string
+		query     string
+		wantQuery string
+		wantArgs  []any
+	}{
+		{"postgres", DriverNamePostgres, "select :a, :b, :a", "select $1, $2, $3", []any{"1", "2", "1"}},
+		{"mysql", DriverNameMySQL, "select :a, ${b}", "select ?, ?", []any{"1", "2"}},
+		{"casts and literals are kept", DriverNamePostgres, "select :a::int, ':b' -- :b", "select $1::int, ':b' -- :b", []any{"1"}},
+		{"postgres backslash", DriverNamePostgres, `select '\', :b`, `select '\', $1`, []any{"2"}},
+		{"array slices", DriverNamePostgres, "select x[lo:hi] from t where a = :a", "select x[lo:hi] from t where a = $1", []any{"1"}},
+		{"no params", DriverNamePostgres, "select 1", "select 1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := BindParams(tt.driver, tt.query, values)
+			if query != tt.wantQuery || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("BindParams(%q) = %q, %v, want %q, %v", tt.query, query, args, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPreviewParams(t *testing.T) {
+	values := map[string]string{"a": "it's", "b": `c:\x`}
+	tests := []struct {
+		name   string
+		driver string
+		query  string
+		want   string
+	}{
+		{"postgres", DriverNamePostgres, "delete from t where a = :a and b = ${b}", `delete from t where a = 'it''s' and b = 'c:\x'`},
+		{"mysql escapes backslashes", DriverNameMySQL, "delete from t where b = :b", `delete from t where b = 'c:\\x'`},
+		{"a missing value", DriverNamePostgres, "select :c, ':a'", "select '', ':a'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PreviewParams(tt.driver, tt.query, values); got != tt.want {
+				t.Errorf("PreviewParams(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnbindPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   string
+		query    string
+		position int
+		want     int
+	}{
+		{"before any params", DriverNamePostgres, "select :a from t", 1, 0},
+		{"within a param", DriverNamePostgres, "select :abc from t", 9, 7},
+		{"after a param", DriverNamePostgres, "select :abc from t", 11, 12},
+		{"after a mysql param", DriverNameMySQL, "select :abc from t", 10, 12},
+		{"after a string", DriverNamePostgres, "select 'x', :a, y", 17, 16},
+		{"after an array slice", DriverNamePostgres, "select a[1:2], :b, y", 20, 19},
+		{"multibyte characters", DriverNamePostgres, "select 'é', :a, y", 17, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnbindPosition(tt.driver, tt.query, tt.position); got != tt.want {
+				t.Errorf("UnbindPosition(%q, %d) = %d, want %d", tt.query, tt.position, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSetVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]string
+	}{
+		{"plain value", "-- @set id = 42\nselect :id", map[string]string{"id": "42"}},
+		{"quoted values", "-- @set a = 'x y'\n--@set b=\"z\";", map[string]string{"a": "x y", "b": "z"}},
+		{"the last one wins", "-- @set a = 1\n-- @set a = 2", map[string]string{"a": "2"}},
+		{"not at the start of a line", "select 1 -- @set a = 1", map[string]string{}},
+		{"other comments", "-- set a = 1\n-- @sets a = 1", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSetVariables(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSetVariables(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
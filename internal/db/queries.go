@@ -6,11 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+var returningRe = regexp.MustCompile(`(?i)\s*(RETURNING)\s+`)
+
+var ErrReadOnly = errors.New("the connection is read only, write statements are not allowed\n\n to allow them remove the 'readOnly' option from the connection config")
+
 type Table struct {
 	Name     string
 	RowCount int
@@ -32,18 +37,30 @@ func GetSchemaTables(dbConn DBConn) (*Data, error) {
         ORDER BY name;`
 	}
 
-	return ExecuteQuery(dbConn, query)
+	return queryMetadata(dbConn, query)
+}
+
+// fetches the column information for the specified table, in the given schema or the one the
+// database finds an unqualified table name in when schema is empty
+func GetTableColumns(dbConn DBConn, schema string, tableName string) (*Data, error) {
+	return queryTableColumns(dbConn, schema, tableName, false)
 }
 
-// fetches the column information for the specified table
-func GetTableColumns(dbConn DBConn, tableName string) (*Data, error) {
-	return ExecuteQuery(dbConn, fmt.Sprintf(`SELECT column_name name, data_type type, case when is_nullable = 'NO' then 'NOT NULL' else 'NULL' end nullable  
+// fetches the columns of the table in order, along with their default values if withDefault
+func queryTableColumns(dbConn DBConn, schema string, tableName string, withDefault bool) (*Data, error) {
+	columns := `column_name name, data_type type, case when is_nullable = 'NO' then 'NOT NULL' else 'NULL' end nullable`
+	if withDefault {
+		columns += `, column_default "default"`
+	}
+	return queryMetadata(dbConn, fmt.Sprintf(`SELECT %s
                                         FROM INFORMATION_SCHEMA.COLUMNS
-                                        WHERE  TABLE_NAME = '%s';`, tableName))
+                                        WHERE  %s
+                                        ORDER BY ordinal_position;`, columns, tableFilter(dbConn.DriverName, schema, tableName)))
 }
 
-// fetches the index information for the specified table
-func GetTableIndexes(dbConn DBConn, tableName string) (*Data, error) {
+// fetches the index information for the specified table, in the given schema or the one the
+// database finds an unqualified table name in when schema is empty
+func GetTableIndexes(dbConn DBConn, schema string, tableName string) (*Data, error) {
 
 	var query string
 	switch dbConn.DriverName {
@@ -56,9 +73,9 @@ func GetTableIndexes(dbConn DBConn, tableName string) (*Data, error) {
                       FROM
                         INFORMATION_SCHEMA.statistics
                       WHERE
-                        TABLE_NAME = '%s'
+                        %s
                         group by index_name, non_unique
-                        order by seq_in_index;`, tableName)
+                        order by seq_in_index;`, tableFilter(dbConn.DriverName, schema, tableName))
 	case DriverNamePostgres:
 		query = fmt.Sprintf(`select
                           i.relname as "name",
@@ -76,7 +93,7 @@ func GetTableIndexes(dbConn DBConn, tableName string) (*Data, error) {
                           and a.attrelid = t.oid
                           and a.attnum = ANY(ix.indkey)
                           and t.relkind = 'r'
-                          and t.relname like '%s'
+                          and t.oid = %s
                       group by
                           t.relname,
                           i.relname,
@@ -84,39 +101,138 @@ func GetTableIndexes(dbConn DBConn, tableName string) (*Data, error) {
                       ix.indisprimary
                       order by
                           t.relname,
-                          i.relname;`, tableName)
+                          i.relname;`, pgTableOid(schema, tableName))
 	}
-	return ExecuteQuery(dbConn, query)
+	return queryMetadata(dbConn, query)
 }
 
-// fetches n rows from the specified table
-func GetTableRows(dbConn DBConn, tableName string) (*Data, error) {
-	return ExecuteQuery(dbConn, fmt.Sprintf("SELECT * FROM %s limit %d;", tableName, getTableDataRowLimit()))
+// a condition on an information_schema view's TABLE_SCHEMA and TABLE_NAME that matches the table,
+// an empty schema means the one the database would find the table in
+func tableFilter(driverName string, schema string, tableName string) string {
+	if driverName == DriverNamePostgres {
+		return fmt.Sprintf(`(TABLE_SCHEMA, TABLE_NAME) IN (SELECT n.nspname, c.relname FROM pg_class c
+                                          JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.oid = %s)`, pgTableOid(schema, tableName))
+	}
+	schemaExpr := "DATABASE()"
+	if schema != "" {
+		schemaExpr = quoteString(schema)
+	}
+	return fmt.Sprintf("TABLE_SCHEMA = %s AND TABLE_NAME = %s", schemaExpr, quoteString(tableName))
 }
 
-// executes a user supplied sql query or statement
-func ExecuteQuery(dbConn DBConn, query string) (*Data, error) {
-	timeoutSecs := getTimeoutSecs()
-	queryCtx, cancel := context.WithTimeout(context.Background(), timeoutSecs*time.Second)
-	defer cancel()
+// the oid of a postgres table, looked up on the search_path when schema is empty
+func pgTableOid(schema string, tableName string) string {
+	name := fmt.Sprintf("quote_ident(%s)", quoteString(tableName))
+	if schema != "" {
+		name = fmt.Sprintf("quote_ident(%s) || '.' || %s", quoteString(schema), name)
+	}
+	return fmt.Sprintf("to_regclass(%s)", name)
+}
 
-	// crude way to decide whether the query should returns rows or use execute
-	isStatement, err := regexp.MatchString(`(?i)^\s*(UPDATE|INSERT|DELETE|DROP|TRUNCATE|CREATE|ALTER)\s+`, query)
-	if err != nil {
-		return nil, err
+// a sql string literal
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// a page of a table's rows, with the filtering and sorting done by the database
+type TableQuery struct {
+	Table string
+	// a sql expression used as the WHERE clause
+	Where   string
+	OrderBy string
+	Desc    bool
+	Page    int
+}
+
+// describes the filtering and sorting, e.g. users WHERE age > 30 ORDER BY name DESC
+func (q TableQuery) Description() string {
+	desc := q.Table
+	if q.Where != "" {
+		desc += " WHERE " + q.Where
+	}
+	if q.OrderBy != "" {
+		desc += " ORDER BY " + q.OrderBy
+		if q.Desc {
+			desc += " DESC"
+		}
+	}
+	return desc
+}
+
+func (q TableQuery) sql(driverName string, limit int) string {
+	query := "SELECT * FROM " + q.Table
+	if q.Where != "" {
+		query += " WHERE " + q.Where
+	}
+	if q.OrderBy != "" {
+		query += " ORDER BY " + quoteIdent(driverName, q.OrderBy)
+		if q.Desc {
+			query += " DESC"
+		}
 	}
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d;", query, limit, q.Page*limit)
+}
 
-	isReturning, err := regexp.MatchString(`(?i)\s*(RETURNING)\s+`, query)
+// fetches a page of rows from the table
+func GetTableRows(dbConn DBConn, q TableQuery) (*Data, error) {
+	limit := getTableDataRowLimit()
+	// fetch one extra row to find out whether there is another page
+	data, err := ExecuteQuery(dbConn, q.sql(dbConn.DriverName, limit+1))
 	if err != nil {
 		return nil, err
 	}
+	if len(data.Rows) > limit {
+		data.Rows = data.Rows[:limit]
+		data.HasMore = true
+	}
+	data.Table = q.Table
+	data.TableQuery = &q
+	return data, nil
+}
+
+// executes a user supplied sql query or statement, with optional bind parameter args
+func ExecuteQuery(dbConn DBConn, query string, args ...any) (*Data, error) {
+	if dbConn.ReadOnly && IsWriteStatement(dbConn.DriverName, query) {
+		return nil, ErrReadOnly
+	}
+
+	timeoutSecs := getTimeoutSecs()
+	queryCtx, cancel := context.WithTimeout(context.Background(), timeoutSecs*time.Second)
+	defer cancel()
+
+	// crude way to decide whether the query should returns rows or use execute, a procedure call can return rows,
+	// and EXPLAIN ANALYZE of a write returns its plan
+	words, _ := sqlKeywords(dbConn.DriverName, query)
+	isReturning := returningRe.MatchString(query) || (len(words) > 0 && (words[0] == "CALL" || words[0] == "EXPLAIN"))
 
-	if isStatement && !isReturning {
-		return execStatement(queryCtx, dbConn, query)
-	} else {
-		return fetchRows(queryCtx, dbConn, query)
+	var data *Data
+	start := time.Now()
+	err := dbConn.withSession(queryCtx, func(runner queryRunner) error {
+		var err error
+		if IsWriteStatement(dbConn.DriverName, query) && !isReturning {
+			data, err = execStatement(queryCtx, runner, dbConn.DriverName, query, args...)
+		} else {
+			data, err = fetchRows(queryCtx, runner, query, args...)
+			if err == nil {
+				data.Table, _ = SourceTable(dbConn.DriverName, query)
+				data.Query, data.Args = query, args
+			}
+		}
+		return err
+	})
+	if data != nil {
+		data.Duration = time.Since(start)
 	}
+	return data, err
+}
 
+// runs an internal query for schema information, outside of the user's session
+func queryMetadata(dbConn DBConn, query string) (*Data, error) {
+	timeoutSecs := getTimeoutSecs()
+	queryCtx, cancel := context.WithTimeout(context.Background(), timeoutSecs*time.Second)
+	defer cancel()
+
+	return fetchRows(queryCtx, dbConn.DB, query)
 }
 
 func getTimeoutSecs() time.Duration {
@@ -135,8 +251,8 @@ func getTableDataRowLimit() int {
 	return rowLimit
 }
 
-func fetchRows(ctx context.Context, dbConn DBConn, query string) (*Data, error) {
-	rows, err := dbConn.DB.QueryContext(ctx, query)
+func fetchRows(ctx context.Context, runner queryRunner, query string, args ...any) (*Data, error) {
+	rows, err := runner.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -181,8 +297,8 @@ func fetchRows(ctx context.Context, dbConn DBConn, query string) (*Data, error)
 	return data, nil
 }
 
-func execStatement(ctx context.Context, dbConn DBConn, query string) (*Data, error) {
-	res, err := dbConn.DB.ExecContext(ctx, query)
+func execStatement(ctx context.Context, runner queryRunner, driverName string, query string, args ...any) (*Data, error) {
+	res, err := runner.ExecContext(ctx, query, args...)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return nil, fmt.Errorf("query timeout exceeded (%d secs)\n\n to change the timeout add or modify the 'queryTimeout` config option", getTimeoutSecs())
@@ -195,7 +311,7 @@ func execStatement(ctx context.Context, dbConn DBConn, query string) (*Data, err
 		return nil, err
 	}
 
-	switch dbConn.DriverName {
+	switch driverName {
 
 	case DriverNameMySQL:
 		lastInsertId, err := res.LastInsertId()
@@ -207,14 +323,16 @@ func execStatement(ctx context.Context, dbConn DBConn, query string) (*Data, err
 			Rows: []map[string]interface{}{{
 				"Rows Affected":    rowsAffected,
 				"Last Inserted ID": lastInsertId,
-			}}}, nil
+			}},
+			RowsAffected: rowsAffected}, nil
 
 	default:
 		return &Data{
 			Columns: []string{"Rows Affected"},
 			Rows: []map[string]interface{}{{
 				"Rows Affected": rowsAffected,
-			}}}, nil
+			}},
+			RowsAffected: rowsAffected}, nil
 	}
 
 }
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrTransactionOpen   = errors.New("a transaction is already open")
+	ErrNoTransactionOpen = errors.New("there is no open transaction")
+)
+
+// satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type queryRunner interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// the state of the user's session, shared by all copies of the DBConn
+//
+// user statements all run on one dedicated connection, so session state such as
+// SET variables and temporary tables persists from one statement to the next
+type Session struct {
+	mu             sync.Mutex
+	conn           *sql.Conn
+	tx             *sql.Tx
+	initStatements []string
+	// whether a transaction is open and how many statements have run in it, kept outside of mu
+	// so they can be read while a statement is running
+	txOpen     atomic.Bool
+	statements atomic.Int64
+	// the connection was lost and re-opened since this was last checked
+	reconnected atomic.Bool
+}
+
+// initStatements are run each time the session's connection is (re)established
+func NewSession(initStatements []string) *Session {
+	return &Session{initStatements: initStatements}
+}
+
+// closes the session's connection, returning it to the pool
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		s.tx.Rollback()
+		s.tx = nil
+		s.txOpen.Store(false)
+	}
+	return s.dropConn()
+}
+
+func (s *Session) dropConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// returns the session's connection, opening it and running the init statements if needed
+func (dbConn DBConn) sessionConn() (*sql.Conn, error) {
+	s := dbConn.Session
+	if s.conn != nil {
+		return s.conn, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getTimeoutSecs()*time.Second)
+	defer cancel()
+
+	conn, err := dbConn.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range s.initStatements {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error running init statement '%s': %w", stmt, err)
+		}
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// reports whether the error means the connection has gone and nothing was sent on it
+func isConnLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+// runs fn with the transaction if one is open, or the session's connection if not,
+// statements run on the session are serialised
+func (dbConn DBConn) withSession(ctx context.Context, fn func(runner queryRunner) error) error {
+	s := dbConn.Session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dbConn.ReadOnly {
+		fn = dbConn.readOnlyStatement(ctx, fn)
+	}
+
+	if s.tx != nil {
+		s.statements.Add(1)
+		return fn(s.tx)
+	}
+
+	conn, err := dbConn.sessionConn()
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	if !isConnLost(err) {
+		return err
+	}
+
+	// the connection dropped before the statement was sent, so reconnect and try again,
+	// any session state not set by the init statements is lost
+	log.Println("session connection lost, reconnecting:", err)
+	s.dropConn()
+	if conn, err = dbConn.sessionConn(); err != nil {
+		return err
+	}
+	s.reconnected.Store(true)
+	return fn(conn)
+}
+
+// on a read only connection a statement outside of a transaction runs in a read only one, so nothing it does
+// (such as a procedure call) can write, and the session is checked to still be read only once it has run
+func (dbConn DBConn) readOnlyStatement(ctx context.Context, fn func(runner queryRunner) error) func(runner queryRunner) error {
+	return func(runner queryRunner) error {
+		conn, ok := runner.(*sql.Conn)
+		if !ok {
+			// the open transaction
+			if err := fn(runner); err != nil {
+				return err
+			}
+			return dbConn.ensureReadOnly(ctx, runner)
+		}
+
+		tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := dbConn.ensureReadOnly(ctx, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// committed rather than rolled back so session settings, such as the search_path, are kept
+		return tx.Commit()
+	}
+}
+
+// checks the session's default is still read only, as a statement such as SELECT set_config(...) can turn it
+// off, in which case it's turned back on and the statement rejected
+func (dbConn DBConn) ensureReadOnly(ctx context.Context, runner queryRunner) error {
+	query, restore, readOnly := "SHOW default_transaction_read_only", "SET SESSION default_transaction_read_only = on", "on"
+	if dbConn.DriverName == DriverNameMySQL {
+		query, restore, readOnly = "SELECT @@session.transaction_read_only", "SET SESSION transaction_read_only = 1", "1"
+	}
+
+	rows, err := runner.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	var value string
+	if rows.Next() {
+		err = rows.Scan(&value)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if value == readOnly {
+		return nil
+	}
+	if _, err := runner.ExecContext(ctx, restore); err != nil {
+		return err
+	}
+	return ErrReadOnly
+}
+
+// reports whether the session's connection has been re-opened since the last call,
+// so the user can be warned that their session state has gone
+func (dbConn DBConn) Reconnected() bool {
+	return dbConn.Session.reconnected.Swap(false)
+}
+
+// runs fn in the open transaction, or if there isn't one in a new transaction (own is true)
+// that is committed when fn succeeds and rolled back when it fails
+func (dbConn DBConn) inTransaction(ctx context.Context, fn func(tx *sql.Tx, own bool) error) error {
+	s := dbConn.Session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		s.statements.Add(1)
+		return fn(s.tx, false)
+	}
+
+	conn, err := dbConn.sessionConn()
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if isConnLost(err) {
+		s.dropConn()
+		if conn, err = dbConn.sessionConn(); err != nil {
+			return err
+		}
+		s.reconnected.Store(true)
+		tx, err = conn.BeginTx(ctx, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(tx, true); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// runs fn in a new read only transaction on the session's connection, so the init statements apply,
+// the transaction is always rolled back
+func (dbConn DBConn) inReadOnlyTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	s := dbConn.Session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		return ErrTransactionOpen
+	}
+	conn, err := dbConn.sessionConn()
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+func (dbConn DBConn) Begin() error {
+	s := dbConn.Session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx != nil {
+		return ErrTransactionOpen
+	}
+	conn, err := dbConn.sessionConn()
+	if err != nil {
+		return err
+	}
+	// the context is used for the life of the transaction, so it can't have a timeout
+	opts := &sql.TxOptions{ReadOnly: dbConn.ReadOnly}
+	tx, err := conn.BeginTx(context.Background(), opts)
+	if isConnLost(err) {
+		s.dropConn()
+		if conn, err = dbConn.sessionConn(); err != nil {
+			return err
+		}
+		s.reconnected.Store(true)
+		tx, err = conn.BeginTx(context.Background(), opts)
+	}
+	if err != nil {
+		return err
+	}
+	s.tx = tx
+	s.txOpen.Store(true)
+	s.statements.Store(0)
+	return nil
+}
+
+func (dbConn DBConn) Commit() error {
+	return dbConn.endTransaction((*sql.Tx).Commit)
+}
+
+func (dbConn DBConn) Rollback() error {
+	return dbConn.endTransaction((*sql.Tx).Rollback)
+}
+
+func (dbConn DBConn) endTransaction(end func(*sql.Tx) error) error {
+	s := dbConn.Session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx == nil {
+		return ErrNoTransactionOpen
+	}
+	// the transaction is finished even if commit fails
+	tx := s.tx
+	s.tx = nil
+	s.txOpen.Store(false)
+	s.statements.Store(0)
+	err := end(tx)
+	if isConnLost(err) {
+		s.dropConn()
+	}
+	return err
+}
+
+type TransactionControl int
+
+const (
+	TransactionControlNone TransactionControl = iota
+	TransactionControlBegin
+	TransactionControlCommit
+	TransactionControlRollback
+)
+
+// recognises BEGIN, START TRANSACTION, COMMIT and ROLLBACK typed as statements, these have
+// to go through the session rather than the pool so they apply to the same connection
+func ParseTransactionControl(driverName string, query string) TransactionControl {
+	words, _ := sqlKeywords(driverName, query)
+	if len(words) == 0 || len(words) > 2 {
+		return TransactionControlNone
+	}
+	if len(words) == 2 && words[1] != "WORK" && words[1] != "TRANSACTION" {
+		return TransactionControlNone
+	}
+	switch {
+	case words[0] == "BEGIN":
+		return TransactionControlBegin
+	case words[0] == "START" && len(words) == 2 && words[1] == "TRANSACTION":
+		return TransactionControlBegin
+	case words[0] == "COMMIT" || words[0] == "END":
+		return TransactionControlCommit
+	case words[0] == "ROLLBACK" || words[0] == "ABORT":
+		return TransactionControlRollback
+	}
+	return TransactionControlNone
+}
+
+// reports whether a transaction is open, and how many statements have been run in it,
+// without waiting for a running statement to finish
+func (dbConn DBConn) TransactionState() (bool, int) {
+	s := dbConn.Session
+	return s.txOpen.Load(), int(s.statements.Load())
+}
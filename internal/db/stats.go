@@ -0,0 +1,195 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the number of most frequent values listed for a text column
+const topValueCount = 10
+
+// a summary of the values in a column
+type ColumnStats struct {
+	Column   string
+	Source   string
+	Count    int64
+	Nulls    int64
+	Distinct int64
+	Min      string
+	Max      string
+	// the mean and median are only set for numeric columns, the top values only for text
+	Numeric bool
+	Mean    float64
+	Median  float64
+	Top     []ValueCount
+}
+
+type ValueCount struct {
+	Value string
+	Count int64
+}
+
+// postgres types that can't be compared or grouped, so their text is used instead
+var textComparedTypes = []string{"boolean", "json", "xml", "point", "line", "polygon", "box", "circle", "path"}
+
+var numericTypes = []string{"int", "integer", "bigint", "smallint", "tinyint", "mediumint", "decimal", "numeric", "real", "double", "double precision", "float"}
+
+// computes the stats of a column from the rows that have been fetched
+func ComputeColumnStats(data *Data, column string) ColumnStats {
+	stats := ColumnStats{Column: column, Source: fmt.Sprintf("%d fetched rows", len(data.Rows)), Numeric: true}
+
+	counts := map[string]int64{}
+	var numbers []float64
+	for _, row := range data.Rows {
+		stats.Count++
+		v := fmt.Sprintf("%v", row[column])
+		if v == "NULL" {
+			stats.Nulls++
+			continue
+		}
+		counts[v]++
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			numbers = append(numbers, f)
+		} else {
+			stats.Numeric = false
+		}
+	}
+	stats.Distinct = int64(len(counts))
+	if len(counts) == 0 {
+		stats.Numeric = false
+		return stats
+	}
+
+	if stats.Numeric {
+		sort.Float64s(numbers)
+		stats.Min = formatNumber(numbers[0])
+		stats.Max = formatNumber(numbers[len(numbers)-1])
+		var sum float64
+		for _, n := range numbers {
+			sum += n
+		}
+		stats.Mean = sum / float64(len(numbers))
+		stats.Median = median(numbers)
+		return stats
+	}
+
+	for v, c := range counts {
+		if stats.Min == "" || v < stats.Min {
+			stats.Min = v
+		}
+		if v > stats.Max {
+			stats.Max = v
+		}
+		stats.Top = append(stats.Top, ValueCount{Value: v, Count: c})
+	}
+	sort.Slice(stats.Top, func(i, j int) bool {
+		if stats.Top[i].Count != stats.Top[j].Count {
+			return stats.Top[i].Count > stats.Top[j].Count
+		}
+		return stats.Top[i].Value < stats.Top[j].Value
+	})
+	if len(stats.Top) > topValueCount {
+		stats.Top = stats.Top[:topValueCount]
+	}
+	return stats
+}
+
+// the median of sorted numbers
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// computes the stats of a column for the whole table, in the database
+func GetColumnStats(dbConn DBConn, table string, column string) (*ColumnStats, error) {
+	schema, name := splitTableName(dbConn.DriverName, table)
+	columns, err := GetTableColumns(dbConn, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ColumnStats{Column: column, Source: "the whole table"}
+	dataType := ""
+	for _, row := range columns.Rows {
+		if row["name"] == column {
+			dataType = strings.ToLower(fmt.Sprintf("%v", row["type"]))
+		}
+	}
+	if dataType == "" {
+		return nil, fmt.Errorf("%s has no column named '%s'", table, column)
+	}
+	stats.Numeric = slices.Contains(numericTypes, dataType)
+
+	col := quoteIdent(dbConn.DriverName, column)
+	if dbConn.DriverName == DriverNamePostgres && slices.Contains(textComparedTypes, dataType) {
+		col += "::text"
+	}
+	data, err := ExecuteQuery(dbConn, fmt.Sprintf(
+		"SELECT COUNT(*) total, COUNT(%[1]s) non_null, COUNT(DISTINCT %[1]s) distinct_values, MIN(%[1]s) min_value, MAX(%[1]s) max_value FROM %[2]s;",
+		col, table))
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Rows) == 0 {
+		return nil, fmt.Errorf("no stats were returned")
+	}
+	row := data.Rows[0]
+	stats.Count = toInt(row["total"])
+	stats.Nulls = stats.Count - toInt(row["non_null"])
+	stats.Distinct = toInt(row["distinct_values"])
+	stats.Min = fmt.Sprintf("%v", row["min_value"])
+	stats.Max = fmt.Sprintf("%v", row["max_value"])
+
+	nonNull := stats.Count - stats.Nulls
+	if nonNull == 0 {
+		stats.Numeric = false
+		return stats, nil
+	}
+
+	if stats.Numeric {
+		data, err := ExecuteQuery(dbConn, fmt.Sprintf("SELECT AVG(%s) mean FROM %s;", col, table))
+		if err != nil {
+			return nil, err
+		}
+		stats.Mean = toFloat(data.Rows[0]["mean"])
+
+		// the middle value (or two values) in order, mysql has no median function
+		limit := 2 - nonNull%2
+		data, err = ExecuteQuery(dbConn, fmt.Sprintf("SELECT %[1]s value FROM %[2]s WHERE %[1]s IS NOT NULL ORDER BY %[1]s LIMIT %[3]d OFFSET %[4]d;",
+			col, table, limit, (nonNull-1)/2))
+		if err != nil {
+			return nil, err
+		}
+		var middle []float64
+		for _, r := range data.Rows {
+			middle = append(middle, toFloat(r["value"]))
+		}
+		if len(middle) > 0 {
+			stats.Median = median(middle)
+		}
+		return stats, nil
+	}
+
+	data, err = ExecuteQuery(dbConn, fmt.Sprintf("SELECT %[1]s value, COUNT(*) frequency FROM %[2]s WHERE %[1]s IS NOT NULL GROUP BY %[1]s ORDER BY 2 DESC, 1 LIMIT %[3]d;",
+		col, table, topValueCount))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range data.Rows {
+		stats.Top = append(stats.Top, ValueCount{Value: fmt.Sprintf("%v", r["value"]), Count: toInt(r["frequency"])})
+	}
+	return stats, nil
+}
+
+func toInt(v any) int64 {
+	return int64(toFloat(v))
+}
@@ -15,29 +15,204 @@ type keyMap struct {
 	ExecuteQuery        key.Binding
 	ViewData            key.Binding
 	ToggleLeftPanel     key.Binding
+	ZoomPanel           key.Binding
+	ShrinkLeftPanel     key.Binding
+	GrowLeftPanel       key.Binding
+	GrowResultsPanel    key.Binding
+	ShrinkResultsPanel  key.Binding
+	ResetLayout         key.Binding
 	SaveQuery           key.Binding
 	ReloadQuery         key.Binding
 	CloseResultRowPopup key.Binding
 	Help                key.Binding
+	CommandPalette      key.Binding
 	NextTab             key.Binding
 	PrevTab             key.Binding
 	OpenInEditor        key.Binding
+	SubmitForm          key.Binding
+	NextField           key.Binding
+	PrevField           key.Binding
+	ConfirmYes          key.Binding
+	ConfirmNo           key.Binding
+	NewBuffer           key.Binding
+	RenameBuffer        key.Binding
+	CloseBuffer         key.Binding
+	NextBuffer          key.Binding
+	PrevBuffer          key.Binding
+	Undo                key.Binding
+	Redo                key.Binding
+	Find                key.Binding
+	Replace             key.Binding
+	ReplaceAll          key.Binding
+	ToggleRegex         key.Binding
+	ToggleMatchCase     key.Binding
+	Explain             key.Binding
+	ExplainAnalyze      key.Binding
+	TreeUp              key.Binding
+	TreeDown            key.Binding
+	TreeToggle          key.Binding
+	TreeCollapse        key.Binding
+	TreeExpand          key.Binding
+	NextColumn          key.Binding
+	PrevColumn          key.Binding
+	SortColumn          key.Binding
+	HideColumn          key.Binding
+	ShowColumns         key.Binding
+	MoveColumnLeft      key.Binding
+	MoveColumnRight     key.Binding
+	FreezeMoreColumns   key.Binding
+	FreezeFewerColumns  key.Binding
+	FilterTableData     key.Binding
+	NextTablePage       key.Binding
+	PrevTablePage       key.Binding
+	ViewCell            key.Binding
+	NextRecord          key.Binding
+	PrevRecord          key.Binding
+	SortFields          key.Binding
+	SearchValue         key.Binding
+	NextMatch           key.Binding
+	PrevMatch           key.Binding
+	ViewerPageUp        key.Binding
+	ViewerPageDown      key.Binding
+	EditRow             key.Binding
+	InsertRow           key.Binding
+	DeleteRow           key.Binding
+	DescribeColumn      key.Binding
+	ChartResults        key.Binding
+	NextChartType       key.Binding
+	CompareResults      key.Binding
+	Copy                key.Binding
+	ShowMessageLog      key.Binding
+	BeginTransaction    key.Binding
+	CommitTransaction   key.Binding
+	RollbackTransaction key.Binding
+}
+
+// the panel a command works in
+type Panel int
+
+const (
+	// the command works in whichever panel is active
+	PanelAny Panel = iota
+	PanelTables
+	PanelTableInfo
+	PanelQuery
+	PanelResults
+	// the command works in a popup, so it is listed in the help but not the command palette
+	PanelPopup
+)
+
+// an action listed in the command palette and the help, run by its key binding
+type Command struct {
+	Binding key.Binding
+	// the help column it is listed in
+	Group string
+	// the command palette makes this panel active before running the command
+	Panel Panel
+}
+
+// the name the command is listed by
+func (c Command) Name() string {
+	return c.Binding.Help().Desc
+}
+
+// Commands returns every command, in the order they are listed. The help is
+// generated from it.
+func (k keyMap) Commands() []Command {
+	return []Command{
+		{k.NextPanel, "panels", PanelAny},
+		{k.PrevPanel, "panels", PanelAny},
+		{k.ToggleLeftPanel, "panels", PanelAny},
+		{k.ZoomPanel, "panels", PanelAny},
+		{k.ShrinkLeftPanel, "panels", PanelAny},
+		{k.GrowLeftPanel, "panels", PanelAny},
+		{k.GrowResultsPanel, "panels", PanelAny},
+		{k.ShrinkResultsPanel, "panels", PanelAny},
+		{k.ResetLayout, "panels", PanelAny},
+
+		{k.ExecuteQuery, "actions", PanelQuery},
+		{k.Explain, "actions", PanelQuery},
+		{k.ExplainAnalyze, "actions", PanelQuery},
+		{k.ViewData, "actions", PanelAny},
+		{k.ViewCell, "actions", PanelResults},
+		{k.DescribeColumn, "actions", PanelAny},
+		{k.ChartResults, "actions", PanelResults},
+		{k.CompareResults, "actions", PanelResults},
+		{k.EditRow, "actions", PanelResults},
+		{k.InsertRow, "actions", PanelResults},
+		{k.DeleteRow, "actions", PanelResults},
+		{k.SaveQuery, "actions", PanelQuery},
+		{k.ReloadQuery, "actions", PanelQuery},
+		{k.OpenInEditor, "actions", PanelQuery},
+
+		{k.PrevColumn, "table data", PanelResults},
+		{k.NextColumn, "table data", PanelResults},
+		{k.SortColumn, "table data", PanelResults},
+		{k.FilterTableData, "table data", PanelResults},
+		{k.PrevTablePage, "table data", PanelResults},
+		{k.NextTablePage, "table data", PanelResults},
+
+		{k.PrevTab, "table info", PanelTableInfo},
+		{k.NextTab, "table info", PanelTableInfo},
+
+		{k.PrevRecord, "records", PanelResults},
+		{k.NextRecord, "records", PanelResults},
+		{k.SortFields, "records", PanelResults},
+
+		{k.HideColumn, "columns", PanelResults},
+		{k.ShowColumns, "columns", PanelResults},
+		{k.MoveColumnLeft, "columns", PanelResults},
+		{k.MoveColumnRight, "columns", PanelResults},
+		{k.FreezeMoreColumns, "columns", PanelResults},
+		{k.FreezeFewerColumns, "columns", PanelResults},
+
+		{k.NewBuffer, "buffers", PanelQuery},
+		{k.RenameBuffer, "buffers", PanelQuery},
+		{k.CloseBuffer, "buffers", PanelQuery},
+		{k.NextBuffer, "buffers", PanelQuery},
+		{k.PrevBuffer, "buffers", PanelQuery},
+
+		{k.Undo, "editing", PanelQuery},
+		{k.Redo, "editing", PanelQuery},
+		{k.Find, "editing", PanelQuery},
+		{k.Replace, "editing", PanelQuery},
+		{k.ReplaceAll, "editing", PanelPopup},
+		{k.ToggleRegex, "editing", PanelPopup},
+		{k.ToggleMatchCase, "editing", PanelPopup},
+
+		{k.BeginTransaction, "transactions", PanelAny},
+		{k.CommitTransaction, "transactions", PanelAny},
+		{k.RollbackTransaction, "transactions", PanelAny},
+
+		{k.ShowMessageLog, "messages", PanelAny},
+		{k.Copy, "messages", PanelPopup},
+
+		{k.CommandPalette, "general", PanelAny},
+		{k.Help, "general", PanelAny},
+		{k.CloseResultRowPopup, "general", PanelPopup},
+		{k.Quit, "general", PanelAny},
+	}
 }
 
 // ShortHelp returns keybindings to be shown in the mini help view. It's part
 // of the key.Map interface.
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Help, k.Quit}
+	return []key.Binding{k.CommandPalette, k.Help, k.Quit}
 }
 
 // FullHelp returns keybindings for the expanded help view. It's part of the
 // key.Map interface.
 func (k keyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{k.NextPanel, k.PrevPanel, k.ToggleLeftPanel},
-		{k.ExecuteQuery, k.ViewData, k.SaveQuery, k.ReloadQuery, k.OpenInEditor},
-		{k.Help, k.CloseResultRowPopup, k.Quit},
+	var columns [][]key.Binding
+	group := ""
+	for _, c := range k.Commands() {
+		if c.Group != group || len(columns) == 0 {
+			columns = append(columns, nil)
+			group = c.Group
+		}
+		columns[len(columns)-1] = append(columns[len(columns)-1], c.Binding)
 	}
+	return columns
 }
 
 var DefaultKeyMap = keyMap{
@@ -49,6 +224,10 @@ var DefaultKeyMap = keyMap{
 		key.WithKeys("?"),
 		key.WithHelp("?", "toggle help"),
 	),
+	CommandPalette: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "command palette"),
+	),
 	// Up: key.NewBinding(
 	// 	key.WithKeys("k", "up"),        // actual keybindings
 	// 	key.WithHelp("↑/k", "move up"), // corresponding help text
@@ -85,6 +264,30 @@ var DefaultKeyMap = keyMap{
 		key.WithKeys("ctrl+t"),
 		key.WithHelp("ctrl+t", "toggle table panel"),
 	),
+	ZoomPanel: key.NewBinding(
+		key.WithKeys("alt+z"),
+		key.WithHelp("alt+z", "zoom panel"),
+	),
+	ShrinkLeftPanel: key.NewBinding(
+		key.WithKeys("ctrl+left"),
+		key.WithHelp("ctrl+←", "narrower tables"),
+	),
+	GrowLeftPanel: key.NewBinding(
+		key.WithKeys("ctrl+right"),
+		key.WithHelp("ctrl+→", "wider tables"),
+	),
+	GrowResultsPanel: key.NewBinding(
+		key.WithKeys("ctrl+up"),
+		key.WithHelp("ctrl+↑", "taller results"),
+	),
+	ShrinkResultsPanel: key.NewBinding(
+		key.WithKeys("ctrl+down"),
+		key.WithHelp("ctrl+↓", "shorter results"),
+	),
+	ResetLayout: key.NewBinding(
+		key.WithKeys("alt+0"),
+		key.WithHelp("alt+0", "reset layout"),
+	),
 	SaveQuery: key.NewBinding(
 		key.WithKeys("ctrl+s"),
 		key.WithHelp("ctrl+s", "save query"),
@@ -109,4 +312,232 @@ var DefaultKeyMap = keyMap{
 		key.WithKeys("ctrl+e"),
 		key.WithHelp("ctrl+e", "open query in editor"),
 	),
+	SubmitForm: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "submit"),
+	),
+	NextField: key.NewBinding(
+		key.WithKeys("tab", "down"),
+		key.WithHelp("tab", "next field"),
+	),
+	PrevField: key.NewBinding(
+		key.WithKeys("shift+tab", "up"),
+		key.WithHelp("shift+tab", "previous field"),
+	),
+	ConfirmYes: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "yes"),
+	),
+	ConfirmNo: key.NewBinding(
+		key.WithKeys("n", "esc"),
+		key.WithHelp("n", "no"),
+	),
+	NewBuffer: key.NewBinding(
+		key.WithKeys("alt+n"),
+		key.WithHelp("alt+n", "new query buffer"),
+	),
+	RenameBuffer: key.NewBinding(
+		key.WithKeys("alt+r"),
+		key.WithHelp("alt+r", "rename query buffer"),
+	),
+	CloseBuffer: key.NewBinding(
+		key.WithKeys("alt+w"),
+		key.WithHelp("alt+w", "close query buffer"),
+	),
+	NextBuffer: key.NewBinding(
+		key.WithKeys("alt+.", "ctrl+pgdown"),
+		key.WithHelp("alt+.", "next query buffer"),
+	),
+	PrevBuffer: key.NewBinding(
+		key.WithKeys("alt+,", "ctrl+pgup"),
+		key.WithHelp("alt+,", "previous query buffer"),
+	),
+	Undo: key.NewBinding(
+		key.WithKeys("ctrl+z"),
+		key.WithHelp("ctrl+z", "undo"),
+	),
+	Redo: key.NewBinding(
+		key.WithKeys("ctrl+y"),
+		key.WithHelp("ctrl+y", "redo"),
+	),
+	Find: key.NewBinding(
+		key.WithKeys("ctrl+f"),
+		key.WithHelp("ctrl+f", "find in query"),
+	),
+	Replace: key.NewBinding(
+		key.WithKeys("alt+h"),
+		key.WithHelp("alt+h", "find and replace in query"),
+	),
+	ReplaceAll: key.NewBinding(
+		key.WithKeys("alt+a"),
+		key.WithHelp("alt+a", "replace all matches"),
+	),
+	ToggleRegex: key.NewBinding(
+		key.WithKeys("alt+x"),
+		key.WithHelp("alt+x", "toggle regex find"),
+	),
+	ToggleMatchCase: key.NewBinding(
+		key.WithKeys("alt+c"),
+		key.WithHelp("alt+c", "toggle match case"),
+	),
+	Explain: key.NewBinding(
+		key.WithKeys("f6"),
+		key.WithHelp("f6", "explain query"),
+	),
+	ExplainAnalyze: key.NewBinding(
+		key.WithKeys("f7"),
+		key.WithHelp("f7", "explain analyze query"),
+	),
+	TreeUp: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "move up"),
+	),
+	TreeDown: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "move down"),
+	),
+	TreeToggle: key.NewBinding(
+		key.WithKeys("enter", " "),
+		key.WithHelp("enter", "expand/collapse"),
+	),
+	TreeCollapse: key.NewBinding(
+		key.WithKeys("left", "h"),
+		key.WithHelp("←/h", "collapse"),
+	),
+	TreeExpand: key.NewBinding(
+		key.WithKeys("right", "l"),
+		key.WithHelp("→/l", "expand"),
+	),
+	NextColumn: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "select next column"),
+	),
+	PrevColumn: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "select previous column"),
+	),
+	SortColumn: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort by column"),
+	),
+	HideColumn: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "hide column"),
+	),
+	ShowColumns: key.NewBinding(
+		key.WithKeys("X"),
+		key.WithHelp("X", "show hidden columns"),
+	),
+	MoveColumnLeft: key.NewBinding(
+		key.WithKeys("<"),
+		key.WithHelp("<", "move column left"),
+	),
+	MoveColumnRight: key.NewBinding(
+		key.WithKeys(">"),
+		key.WithHelp(">", "move column right"),
+	),
+	FreezeMoreColumns: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "freeze another column"),
+	),
+	FreezeFewerColumns: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "freeze one less column"),
+	),
+	FilterTableData: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "filter table data (where)"),
+	),
+	NextTablePage: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next page of table data"),
+	),
+	PrevTablePage: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "previous page of table data"),
+	),
+	NextRecord: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "next record"),
+	),
+	PrevRecord: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "previous record"),
+	),
+	SortFields: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "sort record fields alphabetically"),
+	),
+	ViewCell: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "view cell value"),
+	),
+	SearchValue: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search"),
+	),
+	NextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next match"),
+	),
+	PrevMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "previous match"),
+	),
+	ViewerPageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+	ViewerPageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page down"),
+	),
+	EditRow: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit result row / field"),
+	),
+	InsertRow: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "insert row"),
+	),
+	DeleteRow: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete result row"),
+	),
+	DescribeColumn: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "describe column"),
+	),
+	ChartResults: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "chart results"),
+	),
+	NextChartType: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "next chart type"),
+	),
+	CompareResults: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "compare results"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy the error / message"),
+	),
+	ShowMessageLog: key.NewBinding(
+		key.WithKeys("alt+l"),
+		key.WithHelp("alt+l", "message log"),
+	),
+	BeginTransaction: key.NewBinding(
+		key.WithKeys("f8"),
+		key.WithHelp("f8", "begin transaction"),
+	),
+	CommitTransaction: key.NewBinding(
+		key.WithKeys("f9"),
+		key.WithHelp("f9", "commit transaction"),
+	),
+	RollbackTransaction: key.NewBinding(
+		key.WithKeys("f10"),
+		key.WithHelp("f10", "rollback transaction"),
+	),
 }
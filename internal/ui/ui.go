@@ -1,13 +1,23 @@
 package ui
 
 import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
 	"github.com/wheelibin/qrypad/internal/colour"
 	"github.com/wheelibin/qrypad/internal/commands"
 	"github.com/wheelibin/qrypad/internal/component"
+	"github.com/wheelibin/qrypad/internal/constants"
 	"github.com/wheelibin/qrypad/internal/db"
 	"github.com/wheelibin/qrypad/internal/keys"
 	"github.com/wheelibin/qrypad/internal/style"
@@ -25,8 +35,91 @@ const (
 	QueryPanelMinHeight     = 5
 	TableInfoPanelMinHeight = 8
 	TablePanelMinHeight     = 10
+	LeftPanelMinWidth       = 20
+
+	FormIDQueryParams             = "queryParams"
+	FormIDNewBuffer               = "newBuffer"
+	FormIDEditRow                 = "editRow"
+	FormIDInsertRow               = "insertRow"
+	FormIDTableFilter             = "tableFilter"
+	FormIDDescribeColumn          = "describeColumn"
+	FormIDChart                   = "chart"
+	FormIDCompare                 = "compare"
+	FormIDRenameBuffer            = "renameBuffer"
+	ConfirmIDCloseBuffer          = "closeBuffer"
+	ConfirmIDReloadBuffer         = "reloadBuffer"
+	ConfirmIDExplainAnalyze       = "explainAnalyze"
+	ConfirmIDDestructiveStatement = "destructiveStatement"
+	ConfirmIDQuit                 = "quit"
+	ConfirmIDRowStatement         = "rowStatement"
+)
+
+// the panel sizes (as percentages) used until they are changed, and the limits they can be changed within
+var defaultLayout = commands.Layout{LeftWidth: 25, ResultsHeight: 50}
+
+const (
+	layoutStep       = 5
+	minLeftWidth     = 10
+	maxLeftWidth     = 60
+	minResultsHeight = 10
+	maxResultsHeight = 90
+)
+
+// a border between panels that can be dragged with the mouse
+type divider int
+
+const (
+	dividerNone divider = iota
+	// between the tables panels and the query and results panels
+	dividerLeft
+	// between the query and results panels
+	dividerResults
 )
 
+// the panel names shown in the status bar, by panel index
+var panelNames = []string{"tables", "table info", "query", "results"}
+
+// the panel index of each panel a command can work in
+var commandPanels = map[keys.Panel]int{
+	keys.PanelTables:    PanelIndexTables,
+	keys.PanelTableInfo: PanelIndexTableInfo,
+	keys.PanelQuery:     PanelIndexQuery,
+	keys.PanelResults:   PanelIndexResults,
+}
+
+// the most previous results kept to compare with
+const maxResultHistory = 20
+
+type resultHistoryEntry struct {
+	data      *db.Data
+	fetchedAt time.Time
+}
+
+// what to do with a statement once its placeholder values are known
+type statementAction int
+
+const (
+	statementActionExecute statementAction = iota
+	statementActionExplain
+	statementActionExplainAnalyze
+)
+
+// what to do with the selected result row once its table's primary key is known
+type rowAction int
+
+const (
+	rowActionEdit rowAction = iota
+	rowActionInsert
+	rowActionDelete
+)
+
+// the status bar message once a row action's statement has run
+var rowActionDone = map[rowAction]string{
+	rowActionEdit:   "row updated",
+	rowActionInsert: "row inserted",
+	rowActionDelete: "row deleted",
+}
+
 type bounds struct {
 	x1 int
 	x2 int
@@ -34,6 +127,25 @@ type bounds struct {
 	y2 int
 }
 
+type point struct {
+	x int
+	y int
+}
+
+type mouseClick struct {
+	panelIndex int
+	row        int
+	at         time.Time
+}
+
+const (
+	// two clicks on the same row within this time view it, like enter
+	doubleClickInterval = 400 * time.Millisecond
+	// how far one step of the mouse wheel moves the selected row, or scrolls the query text
+	mouseWheelRows  = 1
+	mouseWheelLines = 3
+)
+
 var appStyle = lipgloss.NewStyle()
 
 type model struct {
@@ -46,37 +158,96 @@ type model struct {
 	titleBar       component.TitlBarModel
 	errorPopup     component.ErrorPopupModel
 	resultRowPopup component.ResultRowPopupModel
+	formPopup      component.FormPopupModel
+	confirmPopup   component.ConfirmPopupModel
+	explainPopup   component.ExplainPopupModel
+	cellViewer     component.CellViewerPopupModel
+	columnStats    component.ColumnStatsPopupModel
+	chartPopup     component.ChartPopupModel
+	diffPopup      component.DiffPopupModel
+	messageLog     component.MessageLogPopupModel
+	commandPalette component.CommandPaletteModel
+	toasts         component.ToastsModel
 	help           help.Model
 
 	// state
 	dbAlias          string
 	db               db.DBConn
+	connect          db.ConnectFunc
 	activePanelIndex int
 	errorMessage     string
 	// loading                bool
-	windowTooSmall         bool
-	width                  int
-	height                 int
-	leftPanelHidden        bool
-	selectablePanelCount   int
-	lastSavedQueryContents string
-	showResultRowPopup     bool
-	showHelpPopup          bool
-	tablePanelBounds       bounds
-	tableInfoPanelBounds   bounds
-	queryPanelBounds       bounds
-	resultsPanelBounds     bounds
-}
-
-func NewModel(dbAlias string, db db.DBConn) model {
+	windowTooSmall bool
+	width          int
+	height         int
+	layout         commands.Layout
+	zoomed         bool
+	dragging       divider
+	// the column of the left divider and the row of the results divider, where a drag can start
+	leftDividerX         int
+	resultsDividerY      int
+	selectablePanelCount int
+	showResultRowPopup   bool
+	showHelpPopup        bool
+	showFormPopup        bool
+	showConfirmPopup     bool
+	showExplainPopup     bool
+	showCellViewer       bool
+	showColumnStats      bool
+	showChartPopup       bool
+	showDiffPopup        bool
+	showMessageLog       bool
+	showCommandPalette   bool
+	paramValues          map[string]string
+	pendingStatement     string
+	pendingAction        statementAction
+	pendingReloadFile    string
+	// the last statement run from the query panel, before its parameters were bound
+	executedStatement    string
+	externallyEditedFile string
+	// the result row being changed, the columns to edit (all of them when nil), and the generated statement
+	rowAction     rowAction
+	editRow       map[string]any
+	editColumns   []string
+	editTable     *db.EditableTable
+	editFields    []string
+	editChanges   []db.ColumnValue
+	editStatement db.GeneratedStatement
+	// earlier results to compare with, the most recent last, and when the current results were fetched
+	resultHistory    []resultHistoryEntry
+	resultsFetchedAt time.Time
+	compareKey       string
+	queryFileWatcher *fsnotify.Watcher
+	// an OSC 52 sequence drawn at the start of the view, for the terminal to copy text to its clipboard
+	terminalCopy         string
+	tablePanelBounds     bounds
+	tableInfoPanelBounds bounds
+	queryPanelBounds     bounds
+	resultsPanelBounds   bounds
+	// the top left corner of each panel's border on the screen, by panel index
+	panelOrigins [4]point
+	lastClick    mouseClick
+}
+
+func NewModel(dbAlias string, db db.DBConn, connect db.ConnectFunc) model {
 	tablePanel := component.NewTablePanelModel()
 	tableInfoPanel := component.NewTableInfoPanelModel()
 	queryPanel := component.NewQueryPanelModel(dbAlias)
+	queryPanel.SetEditorMode(viper.GetString(constants.EditorModeConfigKey))
 	resultsPanel := component.NewResultsPanelModel()
-	statusBar := component.NewStatusBarModel(dbAlias)
+	statusBar := component.NewStatusBarModel(dbAlias, db.Description(), db.ReadOnly)
 	titleBar := component.NewTitlBarModel()
 	errorPopup := component.NewErrorPopupModel()
 	resultRowPopup := component.NewResultRowPopupModel()
+	formPopup := component.NewFormPopupModel()
+	confirmPopup := component.NewConfirmPopupModel()
+	explainPopup := component.NewExplainPopupModel()
+	cellViewer := component.NewCellViewerPopupModel()
+	columnStats := component.NewColumnStatsPopupModel()
+	chartPopup := component.NewChartPopupModel()
+	diffPopup := component.NewDiffPopupModel()
+	messageLog := component.NewMessageLogPopupModel()
+	toasts := component.NewToastsModel()
 
 	help := help.New()
 	help.Styles.FullKey = lipgloss.NewStyle().Foreground(colour.HelpKey)
@@ -85,6 +256,7 @@ func NewModel(dbAlias string, db db.DBConn) model {
 	return model{
 		dbAlias:              dbAlias,
 		db:                   db,
+		connect:              connect,
 		tablePanel:           tablePanel,
 		tableInfoPanel:       tableInfoPanel,
 		queryPanel:           queryPanel,
@@ -93,14 +265,34 @@ func NewModel(dbAlias string, db db.DBConn) model {
 		titleBar:             titleBar,
 		errorPopup:           errorPopup,
 		resultRowPopup:       resultRowPopup,
+		formPopup:            formPopup,
+		confirmPopup:         confirmPopup,
+		explainPopup:         explainPopup,
+		cellViewer:           cellViewer,
+		columnStats:          columnStats,
+		chartPopup:           chartPopup,
+		diffPopup:            diffPopup,
+		messageLog:           messageLog,
+		commandPalette:       component.NewCommandPaletteModel(),
+		toasts:               toasts,
 		help:                 help,
 		selectablePanelCount: 4,
+		layout:               defaultLayout,
+		paramValues:          map[string]string{},
 	}
 }
 
 func (m model) Init() tea.Cmd {
+	var autosave tea.Cmd
+	if interval := viper.GetInt(constants.AutosaveIntervalConfigKey); interval > 0 {
+		autosave = commands.Autosave(time.Duration(interval) * time.Second)
+	}
+
 	// Initialize sub-models
 	return tea.Batch(
+		commands.ReadLayout(),
+		commands.WatchQueryFiles(m.dbAlias),
+		autosave,
 		m.tablePanel.Init(m.db),
 		m.tableInfoPanel.Init(),
 		m.queryPanel.Init(),
@@ -109,6 +301,9 @@ func (m model) Init() tea.Cmd {
 		m.titleBar.Init(),
 		m.errorPopup.Init(),
 		m.resultRowPopup.Init(),
+		m.formPopup.Init(),
+		m.confirmPopup.Init(),
+		m.explainPopup.Init(),
 	)
 }
 
@@ -135,10 +330,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	)
 
 	// update this now so the query text value is updated and can be used below
-	if len(m.errorMessage) == 0 {
+	// (keys typed into a form popup are not meant for the query buffer)
+	_, isKeyMsg := msg.(tea.KeyMsg)
+	if len(m.errorMessage) == 0 && !(isKeyMsg && m.popupCapturesKeys()) {
 		m.queryPanel, cmd = m.queryPanel.Update(msg)
 		cmds = append(cmds, cmd)
 	}
+	if k, ok := msg.(commands.CommandKeyMsg); ok {
+		// the rest of the ui handles a command's key as a key press
+		msg = tea.KeyMsg(k)
+	}
 
 	switch msg := msg.(type) {
 
@@ -151,10 +352,125 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.setPanelsActiveState(m.activePanelIndex)
 	case tea.BlurMsg:
 		m.setPanelsActiveState(-1)
+		if viper.GetBool(constants.AutosaveOnBlurConfigKey) {
+			cmds = append(cmds, m.saveDirtyQueryBuffers())
+		}
+
+	case commands.AutosaveMsg:
+		interval := time.Duration(viper.GetInt(constants.AutosaveIntervalConfigKey)) * time.Second
+		cmds = append(cmds, m.saveDirtyQueryBuffers(), commands.Autosave(interval))
+
+	case commands.LayoutReadMsg:
+		m.layout = msg.Layout
+		m.clampLayout()
+		cmds = append(cmds, m.setLeftPanelHidden(m.layout.LeftHidden))
+
+	case commands.QueryFileWatcherStartedMsg:
+		m.queryFileWatcher = msg.Watcher
+		cmds = append(cmds, commands.WaitForQueryFileChange(m.queryFileWatcher))
+
+	case commands.QueryFileWatchErrMsg:
+		cmds = append(cmds, commands.WaitForQueryFileChange(m.queryFileWatcher))
+		m.showError(msg.Error())
+
+	case commands.QueryFileChangedMsg:
+		cmds = append(cmds, commands.WaitForQueryFileChange(m.queryFileWatcher))
+		// changes made in the editor opened with ctrl+e are reloaded when it closes
+		if msg.FileName != m.externallyEditedFile && !m.showConfirmPopup && m.queryPanel.IsChangedOnDisk(msg.FileName, msg.Contents) {
+			text := fmt.Sprintf("'%s' has been changed outside of qrypad, reload it?", filepath.Base(msg.FileName))
+			if m.queryPanel.IsDirty(msg.FileName) {
+				text += "\n\n(unsaved changes in the buffer will be lost)"
+			}
+			m.pendingReloadFile = msg.FileName
+			m.showConfirmPopup = true
+			m.confirmPopup.SetContent(ConfirmIDReloadBuffer, "query file changed", text)
+		}
+
+	case commands.QueryFileReadMsg:
+		if msg.FileName == m.externallyEditedFile {
+			m.externallyEditedFile = ""
+		}
+		cmds = append(cmds, m.notice(fmt.Sprintf("reloaded %s", filepath.Base(msg.FileName))))
+
+	case commands.QueryFileSavedMsg:
+		cmds = append(cmds, m.notice(fmt.Sprintf("saved %s", filepath.Base(msg.FileName))))
+
+	case commands.StatusMessageExpiredMsg:
+		m.statusBar, cmd = m.statusBar.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case commands.ToastExpiredMsg:
+		m.toasts, cmd = m.toasts.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case commands.CopiedMsg:
+		cmds = append(cmds, m.notice(fmt.Sprintf("copied %s", msg.Description)))
+
+	case commands.TerminalCopyMsg:
+		m.terminalCopy = msg.Sequence
+		cmds = append(cmds, commands.ExpireTerminalCopy(), m.notice(fmt.Sprintf("copied %s (through the terminal)", msg.Description)))
+
+	case commands.TerminalCopyDrawnMsg:
+		m.terminalCopy = ""
 
 	case db.DataMsg:
 		cmds = append(cmds, commands.SetLoading(false))
+		m.addResultHistory(msg)
 		m.resultsPanel.SetData(msg)
+		m.statusBar.SetResult(msg)
+		m.statusBar.SetTransaction(m.db.TransactionState())
+		cmds = append(cmds, m.checkReconnected())
+
+	case commands.TransactionChangedMsg:
+		m.statusBar.SetTransaction(m.db.TransactionState())
+		cmds = append(cmds, m.checkReconnected())
+		if msg.Notice != "" {
+			cmds = append(cmds, m.notify(component.MessageInfo, msg.Notice))
+		}
+
+	case db.EditableTableMsg:
+		cmds = append(cmds, m.continueRowAction(msg))
+
+	case commands.RowStatementExecutedMsg:
+		cmds = append(cmds, commands.SetLoading(false), m.notice(rowActionDone[m.rowAction]), m.checkReconnected())
+		m.statusBar.SetTransaction(m.db.TransactionState())
+		if m.rowAction != rowActionEdit {
+			// reload the table data to show the inserted (or without the deleted) row
+			m.showResultRowPopup = false
+			q := db.TableQuery{Table: m.editTable.Name}
+			if tq := m.resultsPanel.GetTableQuery(); tq != nil {
+				q = *tq
+			}
+			cmds = append(cmds, commands.GetTableRows(m.db, q))
+			break
+		}
+		// the row map is shared with the results table, so this updates it in place
+		for _, cv := range m.editChanges {
+			if cv.Value == nil {
+				m.editRow[cv.Column] = "NULL"
+			} else {
+				m.editRow[cv.Column] = cv.Value
+			}
+		}
+		if m.showResultRowPopup {
+			m.showResultRecord()
+		}
+
+	case db.ExplainMsg:
+		cmds = append(cmds, commands.SetLoading(false))
+		m.explainPopup.SetPlan(msg)
+		m.showExplainPopup = true
+
+	case db.ColumnStatsMsg:
+		cmds = append(cmds, commands.SetLoading(false))
+		m.columnStats.SetStats(*msg)
+		m.showColumnStats = true
+
+	case db.CompareDataMsg:
+		cmds = append(cmds, commands.SetLoading(false))
+		if current := m.resultsPanel.GetData(); current != nil {
+			m.showDiff(current, msg.Data, m.dbAlias, msg.Description)
+		}
 
 	case db.TableInfoDataMsg:
 		cmds = append(cmds, commands.SetLoading(false))
@@ -168,12 +484,89 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case commands.ErrMsg:
 		cmds = append(cmds, commands.SetLoading(false))
-		m.errorMessage = msg.Error()
-		m.errorPopup.SetText(m.errorMessage)
+		m.showDBError(msg.Err)
+		m.statusBar.SetTransaction(m.db.TransactionState())
+		cmds = append(cmds, m.checkReconnected())
+
+	case commands.StatementErrMsg:
+		cmds = append(cmds, commands.SetLoading(false))
+		m.showDBError(msg.Err)
+		m.showErrorPosition(msg.Err, msg.Query)
+		m.statusBar.SetTransaction(m.db.TransactionState())
+		cmds = append(cmds, m.checkReconnected())
+
+	case commands.FormSubmittedMsg:
+		m.showFormPopup = false
+		switch msg.ID {
+		case FormIDQueryParams:
+			for name, value := range msg.Values {
+				m.paramValues[name] = value
+			}
+			cmds = append(cmds, m.performStatementAction(m.pendingStatement, m.pendingAction))
+		case FormIDNewBuffer:
+			cmds = append(cmds, commands.CreateQueryFile(m.dbAlias, msg.Values["name"]))
+		case FormIDRenameBuffer:
+			cmds = append(cmds, commands.RenameQueryFile(m.dbAlias, m.queryPanel.GetFilename(), msg.Values["name"]))
+		case FormIDEditRow:
+			m.reviewRowEdit(msg.Values)
+		case FormIDInsertRow:
+			m.reviewRowInsert(msg.Values)
+		case FormIDTableFilter:
+			if q := m.resultsPanel.GetTableQuery(); q != nil {
+				next := *q
+				next.Where = strings.TrimSpace(msg.Values["where"])
+				next.Page = 0
+				cmds = append(cmds, commands.GetTableRows(m.db, next))
+			}
+		case FormIDChart:
+			m.showChart(msg.Values)
+		case FormIDCompare:
+			cmds = append(cmds, m.compareResults(msg.Values))
+		case FormIDDescribeColumn:
+			if column := strings.TrimSpace(msg.Values["column"]); column != "" {
+				cmds = append(cmds, commands.GetColumnStats(m.db, m.tablePanel.GetSelectedTable(), column))
+			}
+		}
+
+	case commands.FormCancelledMsg:
+		m.showFormPopup = false
+
+	case commands.ConfirmedMsg:
+		m.showConfirmPopup = false
+		switch msg.ID {
+		case ConfirmIDCloseBuffer:
+			cmds = append(cmds, commands.DeleteQueryFile(m.queryPanel.GetFilename()))
+		case ConfirmIDReloadBuffer:
+			cmds = append(cmds, commands.ReadQueryFile(m.pendingReloadFile))
+		case ConfirmIDExplainAnalyze:
+			query, args := db.BindParams(m.db.DriverName, m.pendingStatement, m.paramValues)
+			cmds = append(cmds, commands.Explain(m.db, query, true, args...))
+		case ConfirmIDDestructiveStatement:
+			cmds = append(cmds, m.executeStatement(m.pendingStatement))
+		case ConfirmIDRowStatement:
+			cmds = append(cmds, commands.ExecuteRowStatement(m.db, m.editStatement.Query, m.editStatement.Args...))
+		case ConfirmIDQuit:
+			return m, tea.Sequence(commands.RollbackTransaction(m.db), m.quit())
+		}
+
+	case commands.ConfirmCancelledMsg:
+		m.showConfirmPopup = false
+
+	case commands.CommandChosenMsg:
+		m.showCommandPalette = false
+		cmds = append(cmds, m.runCommand(msg.Command))
+
+	case commands.CommandPaletteCancelledMsg:
+		m.showCommandPalette = false
 
 	case commands.ActivePanelChangedMsg:
 		m.activePanelIndex = int(msg)
 		m.setPanelsActiveState(m.activePanelIndex)
+		m.statusBar.SetPanel(panelNames[m.activePanelIndex])
+		if m.zoomed {
+			// the zoom moves to the newly active panel
+			m.adjustSizes()
+		}
 		if m.activePanelIndex == PanelIndexQuery {
 			m.queryPanel, cmd = m.queryPanel.Update(msg)
 			cmds = append(cmds, cmd)
@@ -198,40 +591,147 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 
 	case tea.MouseMsg:
-		if tea.MouseEvent(msg).Button == tea.MouseButtonLeft {
+		switch {
+		case m.dragging != dividerNone && msg.Action == tea.MouseActionMotion:
+			m.dragDivider(msg.X, msg.Y)
+			return m, nil
 
-			if isInBounds(msg.X, msg.Y, m.tablePanelBounds) {
-				if m.activePanelIndex != PanelIndexTables {
-					cmd = commands.SetActivePanel(PanelIndexTables)
-					cmds = append(cmds, cmd)
-				}
-			} else if isInBounds(msg.X, msg.Y, m.tableInfoPanelBounds) {
-				if m.activePanelIndex != PanelIndexTableInfo {
-					cmd = commands.SetActivePanel(PanelIndexTableInfo)
-					cmds = append(cmds, cmd)
-				}
-			} else if isInBounds(msg.X, msg.Y, m.queryPanelBounds) {
-				if m.activePanelIndex != PanelIndexQuery {
-					cmd = commands.SetActivePanel(PanelIndexQuery)
-					cmds = append(cmds, cmd)
-				}
-			} else if isInBounds(msg.X, msg.Y, m.resultsPanelBounds) {
-				if m.activePanelIndex != PanelIndexResults {
-					cmd = commands.SetActivePanel(PanelIndexResults)
-					cmds = append(cmds, cmd)
-				}
+		case m.dragging != dividerNone && msg.Action == tea.MouseActionRelease:
+			m.dragging = dividerNone
+			return m, commands.SaveLayout(m.layout)
+
+		case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft && m.dividerAt(msg.X, msg.Y) != dividerNone:
+			if !m.popupCapturesKeys() && len(m.errorMessage) == 0 {
+				m.dragging = m.dividerAt(msg.X, msg.Y)
+				return m, nil
 			}
+		}
 
+		// the mouse only works with the panels while there isn't a popup over them
+		if m.popupCapturesKeys() || len(m.errorMessage) > 0 || m.showResultRowPopup || m.showHelpPopup {
+			break
+		}
+		panelIndex := m.panelAt(msg.X, msg.Y)
+		if panelIndex < 0 {
+			break
+		}
+
+		switch {
+		case msg.Action == tea.MouseActionPress && (msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown):
+			// the panel under the pointer is scrolled, and made active so the scrolling shows
+			if m.activePanelIndex != panelIndex {
+				cmds = append(cmds, commands.SetActivePanel(panelIndex))
+			}
+			direction := 1
+			if msg.Button == tea.MouseButtonWheelUp {
+				direction = -1
+			}
+			cmds = append(cmds, m.scrollPanel(panelIndex, direction))
+
+		case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+			if m.activePanelIndex != panelIndex {
+				cmds = append(cmds, commands.SetActivePanel(panelIndex))
+			}
+			cmds = append(cmds, m.clickPanel(panelIndex, msg.X, msg.Y))
 		}
 
 	case tea.KeyMsg:
 
 		if len(m.errorMessage) > 0 {
-			// the error popup is shown, so any key should remove it
-			m.errorMessage = ""
+			// the error popup is shown, it can be copied or looked up in the message log
+			switch {
+			case key.Matches(msg, keys.DefaultKeyMap.Copy):
+				return m, commands.CopyToClipboard(m.errorPopup.GetCopyText(), "the error")
+			case key.Matches(msg, keys.DefaultKeyMap.ShowMessageLog):
+				m.errorMessage = ""
+				m.showMessageLog = true
+			case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup), msg.Type == tea.KeyEnter:
+				m.errorMessage = ""
+			}
+			return m, nil
+		}
+
+		if m.showMessageLog {
+			// the message log takes all keys while it is shown
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) || key.Matches(msg, keys.DefaultKeyMap.ShowMessageLog) {
+				m.showMessageLog = false
+				return m, nil
+			}
+			m.messageLog, cmd = m.messageLog.Update(msg)
+			return m, cmd
+		}
+
+		if m.showCommandPalette {
+			// the command palette takes all keys while it is shown
+			m.commandPalette, cmd = m.commandPalette.Update(msg)
+			return m, cmd
+		}
+
+		if m.showFormPopup {
+			// the form popup takes all keys while it is shown
+			m.formPopup, cmd = m.formPopup.Update(msg)
+			return m, cmd
+		}
+
+		if m.showConfirmPopup {
+			// the confirm popup takes all keys while it is shown
+			m.confirmPopup, cmd = m.confirmPopup.Update(msg)
+			return m, cmd
+		}
+
+		if m.showExplainPopup {
+			// the explain popup takes all keys while it is shown
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) {
+				m.showExplainPopup = false
+				return m, nil
+			}
+			m.explainPopup, cmd = m.explainPopup.Update(msg)
+			return m, cmd
+		}
+
+		if m.showDiffPopup {
+			// the diff popup takes all keys while it is shown
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) {
+				m.showDiffPopup = false
+				return m, nil
+			}
+			m.diffPopup, cmd = m.diffPopup.Update(msg)
+			return m, cmd
+		}
+
+		if m.showChartPopup {
+			// the chart popup takes all keys while it is shown
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) {
+				m.showChartPopup = false
+				return m, nil
+			}
+			m.chartPopup, cmd = m.chartPopup.Update(msg)
+			return m, cmd
+		}
+
+		if m.showColumnStats {
+			// the column stats popup takes all keys while it is shown
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) {
+				m.showColumnStats = false
+			}
 			return m, nil
 		}
 
+		if m.showCellViewer {
+			// the cell viewer takes all keys while it is shown, esc closes it unless it's searching
+			if key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup) && !m.cellViewer.IsSearching() {
+				m.showCellViewer = false
+				return m, nil
+			}
+			m.cellViewer, cmd = m.cellViewer.Update(msg)
+			return m, cmd
+		}
+
+		if m.activePanelIndex == PanelIndexQuery && m.queryPanel.TookKey() {
+			// the key was part of a vim command, such as ctrl+r to redo, or typed into the find bar
+			break
+		}
+
 		switch {
 		case key.Matches(msg, keys.DefaultKeyMap.NextPanel):
 			cmd = commands.SetActivePanel((m.activePanelIndex + 1) % m.selectablePanelCount)
@@ -246,50 +746,187 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 
 		case key.Matches(msg, keys.DefaultKeyMap.ViewData):
+			cmds = append(cmds, m.viewSelected(m.activePanelIndex))
+
+		case key.Matches(msg, keys.DefaultKeyMap.SortColumn),
+			key.Matches(msg, keys.DefaultKeyMap.FilterTableData),
+			key.Matches(msg, keys.DefaultKeyMap.NextTablePage),
+			key.Matches(msg, keys.DefaultKeyMap.PrevTablePage):
+			if m.activePanelIndex == PanelIndexResults && !m.showResultRowPopup && !m.resultsPanel.IsFiltering() {
+				cmds = append(cmds, m.browseTableData(msg))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.NextRecord), key.Matches(msg, keys.DefaultKeyMap.PrevRecord):
+			// the record popup moves through the result rows
+			if m.showResultRowPopup && m.activePanelIndex == PanelIndexResults && !m.resultRowPopup.IsFiltering() {
+				index, total := m.resultsPanel.GetSelectedIndex()
+				if key.Matches(msg, keys.DefaultKeyMap.NextRecord) {
+					index++
+				} else {
+					index--
+				}
+				if index >= 0 && index < total {
+					m.resultsPanel.SelectRow(index)
+					m.showResultRecord()
+				}
+				return m, nil
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.ViewCell):
+			switch {
+			case m.showResultRowPopup && !m.resultRowPopup.IsFiltering():
+				m.cellViewer.SetValue(m.resultRowPopup.GetSelectedField(), m.resultRowPopup.GetSelectedValue())
+				m.showCellViewer = true
+			case !m.showResultRowPopup && m.activePanelIndex == PanelIndexResults && !m.resultsPanel.IsFiltering():
+				if col := m.resultsPanel.GetSelectedColumn(); col != "" && m.resultsPanel.GetSelectedRow() != nil {
+					m.cellViewer.SetValue(col, m.resultsPanel.GetSelectedRow()[col])
+					m.showCellViewer = true
+				}
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.DescribeColumn):
 			switch m.activePanelIndex {
-			case PanelIndexTables:
-				cmds = append(cmds, commands.GetTableRows(m.db, m.tablePanel.GetSelectedTable()))
 			case PanelIndexResults:
-				if !m.showResultRowPopup {
-					m.resultRowPopup.SetData(m.resultsPanel.GetSelectedRow())
-					m.showResultRowPopup = true
+				// the fetched rows are summarised here, rather than the whole table
+				if !m.showResultRowPopup && !m.resultsPanel.IsFiltering() {
+					if stats, ok := m.resultsPanel.DescribeSelectedColumn(); ok {
+						m.columnStats.SetStats(stats)
+						m.showColumnStats = true
+					}
+				}
+			case PanelIndexTables:
+				if table := m.tablePanel.GetSelectedTable(); table != "" {
+					m.showFormPopup = true
+					cmds = append(cmds, m.formPopup.SetFields(FormIDDescribeColumn, fmt.Sprintf("describe a column of %s", table), []component.FormField{
+						{Name: "column", Label: "column"},
+					}))
 				}
 			case PanelIndexTableInfo:
-				if !m.showResultRowPopup {
-					m.resultRowPopup.SetData(m.tableInfoPanel.GetSelectedRow())
-					m.showResultRowPopup = true
+				if m.tableInfoPanel.GetActiveTabIndex() == component.TableInfoTabIndexColumns && !m.showResultRowPopup {
+					if row := m.tableInfoPanel.GetSelectedRow(); row != nil {
+						cmds = append(cmds, commands.GetColumnStats(m.db, m.tablePanel.GetSelectedTable(), fmt.Sprintf("%v", row["name"])))
+					}
 				}
 			}
 
+		case key.Matches(msg, keys.DefaultKeyMap.ChartResults):
+			if m.activePanelIndex == PanelIndexResults && !m.showResultRowPopup && !m.resultsPanel.IsFiltering() && m.resultsPanel.GetColumns() != nil {
+				label, values, kind := component.DefaultChart(m.resultsPanel.GetColumns(), m.resultsPanel.GetVisibleRows())
+				m.showFormPopup = true
+				cmds = append(cmds, m.formPopup.SetFields(FormIDChart, "chart results", []component.FormField{
+					{Name: "label", Label: "label column", Value: label},
+					{Name: "values", Label: "value columns", Value: strings.Join(values, ", "), Placeholder: "numeric columns, separated by commas"},
+					{Name: "type", Label: "chart type", Value: string(kind), Placeholder: "bar, line or sparkline"},
+				}))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.CompareResults):
+			if data := m.resultsPanel.GetData(); m.activePanelIndex == PanelIndexResults && !m.showResultRowPopup &&
+				!m.resultsPanel.IsFiltering() && data != nil && data.Query != "" && len(data.Columns) > 0 {
+				with := ""
+				if len(m.resultHistory) > 0 {
+					with = "1"
+				}
+				m.showFormPopup = true
+				cmds = append(cmds, m.formPopup.SetFields(FormIDCompare, fmt.Sprintf("compare results (%d previous results)", len(m.resultHistory)), []component.FormField{
+					{Name: "with", Label: "compare with", Value: with, Placeholder: "1 for the previous result, 2 for the one before, or a connection alias"},
+					{Name: "key", Label: "key column", Value: data.Columns[0]},
+				}))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.EditRow):
+			if m.activePanelIndex == PanelIndexResults {
+				cmds = append(cmds, m.startRowAction(rowActionEdit))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.InsertRow):
+			if m.activePanelIndex == PanelIndexResults {
+				cmds = append(cmds, m.startRowAction(rowActionInsert))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.DeleteRow):
+			if m.activePanelIndex == PanelIndexResults {
+				cmds = append(cmds, m.startRowAction(rowActionDelete))
+			}
+
 		case key.Matches(msg, keys.DefaultKeyMap.ExecuteQuery):
 			if m.activePanelIndex == PanelIndexQuery {
-				cmds = append(cmds, commands.ExecuteQuery(m.db, m.queryPanel.GetCurrentStatement()))
+				cmds = append(cmds, m.runStatement(m.queryPanel.GetCurrentStatement(), statementActionExecute))
 			}
 
-		case key.Matches(msg, keys.DefaultKeyMap.ToggleLeftPanel):
-			m.leftPanelHidden = !m.leftPanelHidden
-			if m.leftPanelHidden {
-				m.selectablePanelCount = 2
-				m.adjustSizes()
-				if m.activePanelIndex < 2 {
-					return m, commands.SetActivePanel(PanelIndexQuery)
-				}
-			} else {
-				m.selectablePanelCount = 4
-				m.adjustSizes()
+		case key.Matches(msg, keys.DefaultKeyMap.Explain):
+			if m.activePanelIndex == PanelIndexQuery {
+				cmds = append(cmds, m.runStatement(m.queryPanel.GetCurrentStatement(), statementActionExplain))
 			}
 
+		case key.Matches(msg, keys.DefaultKeyMap.ExplainAnalyze):
+			if m.activePanelIndex == PanelIndexQuery {
+				cmds = append(cmds, m.runStatement(m.queryPanel.GetCurrentStatement(), statementActionExplainAnalyze))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.ToggleLeftPanel):
+			cmds = append(cmds, m.setLeftPanelHidden(!m.layout.LeftHidden), commands.SaveLayout(m.layout))
+
+		case key.Matches(msg, keys.DefaultKeyMap.ZoomPanel):
+			m.zoomed = !m.zoomed
+			m.adjustSizes()
+
+		case key.Matches(msg, keys.DefaultKeyMap.ShrinkLeftPanel):
+			cmds = append(cmds, m.resizeLayout(-layoutStep, 0))
+
+		case key.Matches(msg, keys.DefaultKeyMap.GrowLeftPanel):
+			cmds = append(cmds, m.resizeLayout(layoutStep, 0))
+
+		case key.Matches(msg, keys.DefaultKeyMap.GrowResultsPanel):
+			cmds = append(cmds, m.resizeLayout(0, layoutStep))
+
+		case key.Matches(msg, keys.DefaultKeyMap.ShrinkResultsPanel):
+			cmds = append(cmds, m.resizeLayout(0, -layoutStep))
+
+		case key.Matches(msg, keys.DefaultKeyMap.ResetLayout):
+			m.zoomed = false
+			m.layout.LeftWidth = defaultLayout.LeftWidth
+			m.layout.ResultsHeight = defaultLayout.ResultsHeight
+			cmds = append(cmds, m.setLeftPanelHidden(false), commands.SaveLayout(m.layout))
+
 		case key.Matches(msg, keys.DefaultKeyMap.SaveQuery):
 			if m.activePanelIndex == PanelIndexQuery {
-				m.queryPanel.SetDirty(false)
-				m.lastSavedQueryContents = m.queryPanel.GetValue()
-				cmds = append(cmds, commands.SaveQueryFile(m.dbAlias, m.queryPanel.GetValue()))
+				cmds = append(cmds, m.saveQueryFile(m.queryPanel.GetFilename(), m.queryPanel.GetValue()))
 			}
 
 		case key.Matches(msg, keys.DefaultKeyMap.ReloadQuery):
 			if m.activePanelIndex == PanelIndexQuery {
-				m.queryPanel.SetDirty(false)
-				cmds = append(cmds, commands.ReadOrCreateQueryFile(m.dbAlias))
+				cmds = append(cmds, commands.ReadQueryFile(m.queryPanel.GetFilename()))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.NewBuffer):
+			if m.activePanelIndex == PanelIndexQuery {
+				m.showFormPopup = true
+				cmds = append(cmds, m.formPopup.SetFields(FormIDNewBuffer, "new query buffer", []component.FormField{
+					{Name: "name", Label: "name"},
+				}))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.RenameBuffer):
+			if m.activePanelIndex == PanelIndexQuery {
+				m.showFormPopup = true
+				cmds = append(cmds, m.formPopup.SetFields(FormIDRenameBuffer, "rename query buffer", []component.FormField{
+					{Name: "name", Label: "name", Value: m.queryPanel.GetBufferName()},
+				}))
+			}
+
+		case key.Matches(msg, keys.DefaultKeyMap.CloseBuffer):
+			if m.activePanelIndex == PanelIndexQuery {
+				switch {
+				case m.queryPanel.GetBufferCount() <= 1:
+					m.showError("the last query buffer can't be closed")
+				case m.queryPanel.IsBlank():
+					cmds = append(cmds, commands.DeleteQueryFile(m.queryPanel.GetFilename()))
+				default:
+					m.showConfirmPopup = true
+					m.confirmPopup.SetContent(ConfirmIDCloseBuffer, "close query buffer",
+						fmt.Sprintf("closing '%s' deletes its file:\n%s", m.queryPanel.GetBufferName(), m.queryPanel.GetFilename()))
+				}
 			}
 
 		case key.Matches(msg, keys.DefaultKeyMap.CloseResultRowPopup):
@@ -299,23 +936,48 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.help.ShowAll = true
 			m.showHelpPopup = !m.showHelpPopup
 
+		case key.Matches(msg, keys.DefaultKeyMap.ShowMessageLog):
+			m.showMessageLog = true
+
+		case key.Matches(msg, keys.DefaultKeyMap.CommandPalette):
+			m.showHelpPopup = false
+			m.showCommandPalette = true
+			// the palette would take the key that opened it as a key to close it
+			return m, tea.Batch(append(cmds, m.commandPalette.Reset())...)
+
+		case key.Matches(msg, keys.DefaultKeyMap.BeginTransaction):
+			cmds = append(cmds, commands.BeginTransaction(m.db))
+
+		case key.Matches(msg, keys.DefaultKeyMap.CommitTransaction):
+			cmds = append(cmds, commands.CommitTransaction(m.db))
+
+		case key.Matches(msg, keys.DefaultKeyMap.RollbackTransaction):
+			cmds = append(cmds, commands.RollbackTransaction(m.db))
+
 		case key.Matches(msg, keys.DefaultKeyMap.Quit):
-			return m, tea.Quit
+			if open, statements := m.db.TransactionState(); open {
+				m.showConfirmPopup = true
+				m.confirmPopup.SetContent(ConfirmIDQuit, "quit",
+					fmt.Sprintf("a transaction is still open (%d statements), quitting will roll it back", statements))
+				return m, nil
+			}
+			return m, m.quit()
 
 		case key.Matches(msg, keys.DefaultKeyMap.OpenInEditor):
+			m.externallyEditedFile = m.queryPanel.GetFilename()
 			return m, commands.OpenEditor(m.queryPanel.GetFilename())
-
-		default:
-			// any other key
-			if m.activePanelIndex == PanelIndexQuery {
-				if m.lastSavedQueryContents != m.queryPanel.GetValue() {
-					m.queryPanel.SetDirty(true)
-				}
-			}
 		}
 	}
 
 	// update components
+	if m.showFormPopup {
+		m.formPopup, cmd = m.formPopup.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.showCommandPalette {
+		m.commandPalette, cmd = m.commandPalette.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 	if m.showResultRowPopup {
 		m.resultRowPopup, cmd = m.resultRowPopup.Update(msg)
 		cmds = append(cmds, cmd)
@@ -344,12 +1006,454 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+func (m *model) saveDirtyQueryBuffers() tea.Cmd {
+	var cmds []tea.Cmd
+	for filename, contents := range m.queryPanel.GetDirtyBuffers() {
+		cmds = append(cmds, m.saveQueryFile(filename, contents))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *model) saveQueryFile(filename string, contents string) tea.Cmd {
+	m.queryPanel.MarkSaving(filename, contents)
+	return commands.SaveQueryFile(filename, contents)
+}
+
+// stops watching the query files and quits
+func (m model) quit() tea.Cmd {
+	return tea.Sequence(commands.CloseQueryFileWatcher(m.queryFileWatcher), tea.Quit)
+}
+
+// shows the highlighted result row in the record popup
+func (m *model) showResultRecord() {
+	m.resultRowPopup.SetData(m.resultsPanel.GetColumns(), m.resultsPanel.GetSelectedRow())
+	m.resultRowPopup.SetPosition(m.resultsPanel.GetSelectedIndex())
+}
+
+// charts the results panel rows with the columns chosen in the chart form
+func (m *model) showChart(values map[string]string) {
+	columns := m.resultsPanel.GetColumns()
+	label := strings.TrimSpace(values["label"])
+	if !slices.Contains(columns, label) {
+		m.showError(fmt.Sprintf("there is no column named '%s' in the results", label))
+		return
+	}
+	var series []string
+	for _, c := range strings.Split(values["values"], ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !slices.Contains(columns, c) {
+			m.showError(fmt.Sprintf("there is no column named '%s' in the results", c))
+			return
+		}
+		series = append(series, c)
+	}
+	kind, ok := component.ParseChartKind(values["type"])
+	if !ok {
+		m.showError(fmt.Sprintf("'%s' isn't a chart type, use bar, line or sparkline", values["type"]))
+		return
+	}
+	if err := m.chartPopup.SetChart(label, series, m.resultsPanel.GetVisibleRows(), kind); err != nil {
+		m.showError(err.Error())
+		return
+	}
+	m.showChartPopup = true
+}
+
+// keeps the results being replaced, so they can be compared with later ones
+func (m *model) addResultHistory(next *db.Data) {
+	current := m.resultsPanel.GetData()
+	if current != nil && current != next && current.Query != "" {
+		m.resultHistory = append(m.resultHistory, resultHistoryEntry{data: current, fetchedAt: m.resultsFetchedAt})
+		if len(m.resultHistory) > maxResultHistory {
+			m.resultHistory = m.resultHistory[1:]
+		}
+	}
+	m.resultsFetchedAt = time.Now()
+}
+
+// compares the current results with an earlier result, or with the same query run against another database
+func (m *model) compareResults(values map[string]string) tea.Cmd {
+	current := m.resultsPanel.GetData()
+	if current == nil {
+		return nil
+	}
+	m.compareKey = strings.TrimSpace(values["key"])
+	with := strings.TrimSpace(values["with"])
+	if with == "" {
+		return m.showError("choose a previous result or a connection alias to compare with")
+	}
+
+	n, err := strconv.Atoi(with)
+	if err != nil {
+		return commands.FetchForCompare(m.connect, with, m.db.DriverName, current.Query, current.Args...)
+	}
+	if n < 1 || n > len(m.resultHistory) {
+		return m.showError(fmt.Sprintf("there are %d previous results to compare with", len(m.resultHistory)))
+	}
+	entry := m.resultHistory[len(m.resultHistory)-n]
+	m.showDiff(entry.data, current, fmt.Sprintf("result %d (%s)", n, entry.fetchedAt.Format(time.TimeOnly)), "current results")
+	return nil
+}
+
+func (m *model) showDiff(from, to *db.Data, fromDesc, toDesc string) {
+	diff, err := db.DiffResults(from, to, m.compareKey)
+	if err != nil {
+		m.showError(err.Error())
+		return
+	}
+	m.diffPopup.SetDiff(diff, fromDesc, toDesc)
+	m.showDiffPopup = true
+}
+
+// changes the sorting, filtering or page of the table data, which is done by the database
+func (m *model) browseTableData(msg tea.KeyMsg) tea.Cmd {
+	q := m.resultsPanel.GetTableQuery()
+	if q == nil {
+		// other results are sorted by the results panel
+		if key.Matches(msg, keys.DefaultKeyMap.SortColumn) {
+			return nil
+		}
+		return m.showError("filtering and paging are done by the database for table data (press enter on a table)")
+	}
+	next := *q
+
+	switch {
+	case key.Matches(msg, keys.DefaultKeyMap.SortColumn):
+		// cycles through ascending, descending, then unsorted
+		col := m.resultsPanel.GetSelectedColumn()
+		switch {
+		case next.OrderBy != col:
+			next.OrderBy, next.Desc = col, false
+		case !next.Desc:
+			next.Desc = true
+		default:
+			next.OrderBy, next.Desc = "", false
+		}
+		next.Page = 0
+
+	case key.Matches(msg, keys.DefaultKeyMap.FilterTableData):
+		m.showFormPopup = true
+		return m.formPopup.SetFields(FormIDTableFilter, "filter "+q.Table, []component.FormField{
+			{Name: "where", Label: "WHERE", Value: q.Where, Placeholder: "e.g. created_at > '2024-01-01'"},
+		})
+
+	case key.Matches(msg, keys.DefaultKeyMap.NextTablePage):
+		if !m.resultsPanel.HasMore() {
+			return nil
+		}
+		next.Page++
+
+	case key.Matches(msg, keys.DefaultKeyMap.PrevTablePage):
+		if next.Page == 0 {
+			return nil
+		}
+		next.Page--
+	}
+	return commands.GetTableRows(m.db, next)
+}
+
+// starts an edit, insert or delete for the results, the edit is of the selected row or just the
+// selected field when the record popup is shown, the table's primary key is looked up first
+func (m *model) startRowAction(action rowAction) tea.Cmd {
+	if m.showResultRowPopup && m.resultRowPopup.IsFiltering() || !m.showResultRowPopup && m.resultsPanel.IsFiltering() {
+		return nil
+	}
+	if m.db.ReadOnly {
+		return m.showError(db.ErrReadOnly.Error())
+	}
+	table := m.resultsPanel.GetTable()
+	if table == "" {
+		return m.showError("only results selected from a single table (without joins or grouping) can be changed")
+	}
+
+	m.rowAction = action
+	m.editRow = m.resultsPanel.GetSelectedRow()
+	if m.editRow == nil && action != rowActionInsert {
+		return m.showError("there is no row selected")
+	}
+	m.editColumns = nil
+	if m.showResultRowPopup {
+		m.editColumns = []string{m.resultRowPopup.GetSelectedField()}
+	}
+	return commands.GetEditableTable(m.db, table)
+}
+
+func (m *model) showError(text string) tea.Cmd {
+	m.errorMessage = text
+	m.errorPopup.SetText(m.errorMessage)
+	m.logMessage(component.MessageError, text, "")
+	return nil
+}
+
+// shows an error from the database, with its error code kept apart from the message
+func (m *model) showDBError(err error) tea.Cmd {
+	details := db.DescribeError(err)
+	m.errorMessage = details.Message
+	m.errorPopup.SetError(details)
+	m.logMessage(component.MessageError, details.String(), details.Code)
+	return nil
+}
+
+// moves the query panel cursor to where the error is in the statement that was run, and shows the line in the error popup
+func (m *model) showErrorPosition(err error, query string) {
+	position := db.ErrorPosition(err, query)
+	if position == 0 {
+		return
+	}
+	// the position is in the query as sent, which only matches the statement if it was bound from it
+	if bound, _ := db.BindParams(m.db.DriverName, m.executedStatement, m.paramValues); bound != query {
+		return
+	}
+	offset := db.UnbindPosition(m.db.DriverName, m.executedStatement, position)
+	if p, ok := m.queryPanel.MoveToError(m.executedStatement, offset); ok {
+		m.errorPopup.SetPosition(p)
+	}
+}
+
+func (m *model) logMessage(level component.MessageLevel, text, code string) {
+	m.messageLog.Add(component.LogEntry{Time: time.Now(), Level: level, Text: text, Code: code})
+}
+
+// shows a toast that goes away by itself, and keeps it in the message log
+func (m *model) notify(level component.MessageLevel, text string) tea.Cmd {
+	m.logMessage(level, text, "")
+	return m.toasts.Add(level, text)
+}
+
+// shows a short message in the status bar, and keeps it in the message log
+func (m *model) notice(text string) tea.Cmd {
+	m.logMessage(component.MessageInfo, text, "")
+	return m.statusBar.ShowMessage(text)
+}
+
+// warns when the connection had to be re-opened, as the session state was lost with it
+func (m *model) checkReconnected() tea.Cmd {
+	if !m.db.Reconnected() {
+		return nil
+	}
+	return m.notify(component.MessageWarning, "the connection was lost and re-opened, session state such as SET variables and temporary tables has been reset")
+}
+
+// the primary key values of the row being changed
+func (m *model) rowKey() ([]db.ColumnValue, error) {
+	key := []db.ColumnValue{}
+	for _, pk := range m.editTable.PrimaryKey {
+		value, ok := m.editRow[pk]
+		if !ok {
+			return nil, fmt.Errorf("the primary key column '%s' isn't in the results, so the row can't be changed", pk)
+		}
+		key = append(key, db.ColumnValue{Column: pk, Value: value})
+	}
+	return key, nil
+}
+
+// carries on with the row action once the table's primary key is known
+func (m *model) continueRowAction(table *db.EditableTable) tea.Cmd {
+	m.editTable = table
+	switch m.rowAction {
+	case rowActionInsert:
+		return m.insertRowFields()
+	case rowActionDelete:
+		key, err := m.rowKey()
+		if err != nil {
+			return m.showError(err.Error())
+		}
+		m.reviewRowStatement("review delete", db.BuildDelete(m.db.DriverName, table.Name, key))
+		return nil
+	default:
+		return m.editRowFields()
+	}
+}
+
+// shows the form for editing the row
+func (m *model) editRowFields() tea.Cmd {
+	if _, err := m.rowKey(); err != nil {
+		return m.showError(err.Error())
+	}
+
+	columns := m.editColumns
+	if columns == nil {
+		columns = m.resultsPanel.GetColumns()
+	}
+	m.editFields = nil
+	fields := []component.FormField{}
+	for _, col := range columns {
+		if !m.editTable.HasColumn(col) || m.editTable.IsPrimaryKey(col) {
+			continue
+		}
+		m.editFields = append(m.editFields, col)
+		fields = append(fields, component.FormField{Name: col, Label: col, Value: fmt.Sprintf("%v", m.editRow[col])})
+	}
+	if len(fields) == 0 {
+		if m.editColumns != nil {
+			return m.showError(fmt.Sprintf("'%s' is a primary key or isn't a column of %s, so it can't be edited", m.editColumns[0], m.editTable.Name))
+		}
+		return m.showError(fmt.Sprintf("none of the result columns can be edited in %s", m.editTable.Name))
+	}
+
+	m.showFormPopup = true
+	return m.formPopup.SetFields(FormIDEditRow, "edit "+m.editTable.Name+" (NULL for null)", fields)
+}
+
+// shows the form for a new row, with a field for every column of the table
+func (m *model) insertRowFields() tea.Cmd {
+	m.editFields = nil
+	fields := []component.FormField{}
+	for _, col := range m.editTable.Columns {
+		placeholder := "required"
+		switch {
+		case col.Default != "":
+			placeholder = "default " + col.Default
+		case col.Nullable:
+			placeholder = "NULL"
+		}
+		m.editFields = append(m.editFields, col.Name)
+		fields = append(fields, component.FormField{Name: col.Name, Label: col.Name + " " + col.Type, Placeholder: placeholder})
+	}
+
+	m.showFormPopup = true
+	return m.formPopup.SetFields(FormIDInsertRow, "insert into "+m.editTable.Name+" (empty for the default)", fields)
+}
+
+// generates the UPDATE for the changed fields
+func (m *model) reviewRowEdit(values map[string]string) {
+	m.editChanges = nil
+	for _, col := range m.editFields {
+		value := values[col]
+		if value == fmt.Sprintf("%v", m.editRow[col]) {
+			continue
+		}
+		if value == "NULL" {
+			m.editChanges = append(m.editChanges, db.ColumnValue{Column: col})
+		} else {
+			m.editChanges = append(m.editChanges, db.ColumnValue{Column: col, Value: value})
+		}
+	}
+	if len(m.editChanges) == 0 {
+		return
+	}
+
+	key, err := m.rowKey()
+	if err != nil {
+		m.showError(err.Error())
+		return
+	}
+	m.reviewRowStatement("review update", db.BuildUpdate(m.db.DriverName, m.editTable.Name, m.editChanges, key))
+}
+
+// generates the INSERT for the fields that were filled in
+func (m *model) reviewRowInsert(values map[string]string) {
+	set := []db.ColumnValue{}
+	for _, col := range m.editFields {
+		switch value := values[col]; value {
+		case "":
+		case "NULL":
+			set = append(set, db.ColumnValue{Column: col})
+		default:
+			set = append(set, db.ColumnValue{Column: col, Value: value})
+		}
+	}
+	m.reviewRowStatement("review insert", db.BuildInsert(m.db.DriverName, m.editTable.Name, set))
+}
+
+// shows a generated statement for review before it is run
+func (m *model) reviewRowStatement(title string, statement db.GeneratedStatement) {
+	m.editStatement = statement
+
+	note := "the statement runs in a transaction, which is only committed if it changes one row"
+	if open, _ := m.db.TransactionState(); open {
+		note = "the statement runs in the open transaction"
+	}
+	m.showConfirmPopup = true
+	m.confirmPopup.SetContent(ConfirmIDRowStatement, title,
+		fmt.Sprintf("%s\n\n%s\n\nconnection: %s (%s)", statement.Preview, note, m.dbAlias, m.db.Description()))
+}
+
+// reports whether a popup is shown that should receive all key presses
+func (m model) popupCapturesKeys() bool {
+	return m.showFormPopup || m.showConfirmPopup || m.showExplainPopup || m.showCellViewer || m.showColumnStats || m.showChartPopup || m.showDiffPopup || m.showMessageLog || m.showCommandPalette
+}
+
+// runs (or explains) the statement, first asking for the values of any placeholders not set by `-- @set` lines
+func (m *model) runStatement(statement string, action statementAction) tea.Cmd {
+	vars := db.ParseSetVariables(m.queryPanel.GetValue())
+	statement = db.RemoveSetVariables(statement)
+
+	fields := []component.FormField{}
+	for _, name := range db.QueryParams(m.db.DriverName, statement) {
+		if value, ok := vars[name]; ok {
+			m.paramValues[name] = value
+			continue
+		}
+		fields = append(fields, component.FormField{Name: name, Label: name, Value: m.paramValues[name]})
+	}
+
+	if len(fields) > 0 {
+		m.pendingStatement = statement
+		m.pendingAction = action
+		m.showFormPopup = true
+		return m.formPopup.SetFields(FormIDQueryParams, "query parameters", fields)
+	}
+	return m.performStatementAction(statement, action)
+}
+
+// binds the placeholder values and executes or explains the statement
+func (m *model) performStatementAction(statement string, action statementAction) tea.Cmd {
+	query, args := db.BindParams(m.db.DriverName, statement, m.paramValues)
+
+	switch action {
+	case statementActionExplain:
+		return commands.Explain(m.db, query, false, args...)
+
+	case statementActionExplainAnalyze:
+		// EXPLAIN ANALYZE runs the statement, so make sure that's ok for writes
+		if db.IsWriteStatement(m.db.DriverName, statement) {
+			m.pendingStatement = statement
+			m.showConfirmPopup = true
+			m.confirmPopup.SetContent(ConfirmIDExplainAnalyze, "explain analyze",
+				fmt.Sprintf("EXPLAIN ANALYZE executes the statement, so its changes will be made:\n\n%s",
+					strings.TrimSpace(db.PreviewParams(m.db.DriverName, statement, m.paramValues))))
+			return nil
+		}
+		return commands.Explain(m.db, query, true, args...)
+
+	default:
+		switch db.ParseTransactionControl(m.db.DriverName, statement) {
+		case db.TransactionControlBegin:
+			return commands.BeginTransaction(m.db)
+		case db.TransactionControlCommit:
+			return commands.CommitTransaction(m.db)
+		case db.TransactionControlRollback:
+			return commands.RollbackTransaction(m.db)
+		}
+		if class, ok := m.db.NeedsConfirmation(statement); ok {
+			m.pendingStatement = statement
+			m.showConfirmPopup = true
+			m.confirmPopup.SetContent(ConfirmIDDestructiveStatement, "confirm statement",
+				fmt.Sprintf("%s\n\n%s\n\nconnection: %s (%s)", class.Description(),
+					strings.TrimSpace(db.PreviewParams(m.db.DriverName, statement, m.paramValues)), m.dbAlias, m.db.Description()))
+			return nil
+		}
+		return m.executeStatement(statement)
+	}
+}
+
+// runs a statement from the query panel, keeping it so the position of an error can be found in the buffer
+func (m *model) executeStatement(statement string) tea.Cmd {
+	m.executedStatement = statement
+	query, args := db.BindParams(m.db.DriverName, statement, m.paramValues)
+	return commands.ExecuteQuery(m.db, query, args...)
+}
+
 func (m *model) adjustSizes() {
 	m.windowTooSmall = false
 
 	availableHeight := m.height - TitleBarHeight - StatusBarHeight
-	leftWidth := style.GetSpan(3, m.width)
-	rightWidth := m.getRightWidth(m.width)
+	leftWidth := max(m.width*m.layout.LeftWidth/100, LeftPanelMinWidth)
+	rightWidth := m.getRightWidth(leftWidth)
 
 	//left
 	tableInfoHeight := style.GetSpan(3, availableHeight)
@@ -363,11 +1467,9 @@ func (m *model) adjustSizes() {
 	m.tableInfoPanel.SetSize(leftWidth, tableInfoHeight)
 	m.tablePanel.SetSize(leftWidth, tableHeight)
 
-	// right
-	resultsHeight := style.GetSpan(6, availableHeight)
-	if resultsHeight < ResultsPanelMinHeight {
-		resultsHeight = ResultsPanelMinHeight
-	}
+	// right, the results are made shorter if the query panel would be too small
+	resultsHeight := (availableHeight - 4) * m.layout.ResultsHeight / 100
+	resultsHeight = max(min(resultsHeight, availableHeight-QueryPanelMinHeight-4), ResultsPanelMinHeight)
 	queryHeight := availableHeight - resultsHeight - 4
 	if queryHeight < QueryPanelMinHeight {
 		m.windowTooSmall = true
@@ -380,22 +1482,224 @@ func (m *model) adjustSizes() {
 	m.tableInfoPanelBounds = bounds{x1: 1, x2: leftWidth + 1, y1: 2 + tableHeight, y2: m.height - StatusBarHeight - 1}
 	m.queryPanelBounds = bounds{x1: leftWidth + 4, x2: m.width - 1, y1: 1, y2: queryHeight + 1}
 	m.resultsPanelBounds = bounds{x1: leftWidth + 2, x2: m.width - 1, y1: queryHeight + 2, y2: m.height - StatusBarHeight - 1}
+	m.leftDividerX = leftWidth + 4
+	m.resultsDividerY = TitleBarHeight + queryHeight + 2
+	m.panelOrigins = [4]point{
+		PanelIndexTables:    {x: 2, y: TitleBarHeight},
+		PanelIndexTableInfo: {x: 2, y: TitleBarHeight + tableHeight + 2},
+		PanelIndexQuery:     {x: leftWidth + 5, y: TitleBarHeight},
+		PanelIndexResults:   {x: leftWidth + 5, y: TitleBarHeight + queryHeight + 2},
+	}
+	if m.layout.LeftHidden {
+		m.queryPanelBounds.x1, m.resultsPanelBounds.x1 = 1, 1
+		m.leftDividerX = -1
+		m.panelOrigins[PanelIndexQuery].x, m.panelOrigins[PanelIndexResults].x = 3, 3
+	}
+
+	if m.zoomed {
+		// the active panel takes all the space, and the others are not drawn
+		zoomedWidth := m.width - 4
+		zoomedHeight := availableHeight - 2
+		zoomedBounds := bounds{x1: 0, x2: m.width, y1: TitleBarHeight - 1, y2: m.height - StatusBarHeight}
+		m.tablePanelBounds, m.tableInfoPanelBounds, m.queryPanelBounds, m.resultsPanelBounds = bounds{}, bounds{}, bounds{}, bounds{}
+		m.leftDividerX, m.resultsDividerY = -1, -1
+		m.panelOrigins[m.activePanelIndex] = point{x: 1, y: TitleBarHeight}
+		switch m.activePanelIndex {
+		case PanelIndexTables:
+			m.tablePanel.SetSize(zoomedWidth, zoomedHeight)
+			m.tablePanelBounds = zoomedBounds
+		case PanelIndexTableInfo:
+			m.tableInfoPanel.SetSize(zoomedWidth, zoomedHeight)
+			m.tableInfoPanelBounds = zoomedBounds
+		case PanelIndexQuery:
+			m.queryPanel.SetSize(zoomedWidth, zoomedHeight)
+			m.queryPanelBounds = zoomedBounds
+		case PanelIndexResults:
+			m.resultsPanel.SetSize(zoomedWidth, zoomedHeight)
+			m.resultsPanelBounds = zoomedBounds
+		}
+	}
 
 	// set non panel component sizes
 	m.statusBar.SetSize(m.width, StatusBarHeight)
 	m.titleBar.SetSize(m.width, TitleBarHeight)
 	m.errorPopup.SetSize(m.width/2, 5)
 	m.resultRowPopup.SetSize(m.width/2, m.height/2)
+	m.formPopup.SetSize(m.width/2, m.height*3/4)
+	m.confirmPopup.SetSize(m.width/2, 0)
+	m.explainPopup.SetSize(m.width*3/4, m.height*3/4)
+	m.cellViewer.SetSize(m.width*3/4, m.height*3/4)
+	m.columnStats.SetSize(m.width/2, m.height/2)
+	m.chartPopup.SetSize(m.width*3/4, m.height*3/4)
+	m.diffPopup.SetSize(m.width*7/8, m.height*3/4)
+	m.messageLog.SetSize(m.width*3/4, m.height*3/4)
+	m.commandPalette.SetSize(m.width/2, m.height*3/4)
+	m.toasts.SetSize(min(m.width/3, 60))
 
 	m.help.Width = m.width
 }
 
-func (m model) getRightWidth(totalWidth int) int {
-	if m.leftPanelHidden {
-		return style.GetSpan(12, totalWidth) - 6
+func (m model) getRightWidth(leftWidth int) int {
+	if m.layout.LeftHidden {
+		return m.width - 6
 	} else {
-		return style.GetSpan(9, totalWidth) - 8
+		return m.width - leftWidth - 8
+	}
+}
+
+// the index of the panel at the screen position, or -1 if there isn't one
+func (m model) panelAt(x int, y int) int {
+	for i, b := range []bounds{m.tablePanelBounds, m.tableInfoPanelBounds, m.queryPanelBounds, m.resultsPanelBounds} {
+		if isInBounds(x, y, b) {
+			return i
+		}
+	}
+	return -1
+}
+
+// scrolls the panel up (direction < 0) or down with the mouse wheel
+func (m *model) scrollPanel(panelIndex int, direction int) tea.Cmd {
+	switch panelIndex {
+	case PanelIndexTables:
+		return m.tablePanel.ScrollBy(direction * mouseWheelRows)
+	case PanelIndexTableInfo:
+		m.tableInfoPanel.ScrollBy(direction * mouseWheelRows)
+	case PanelIndexQuery:
+		m.queryPanel.ScrollBy(direction * mouseWheelLines)
+	case PanelIndexResults:
+		m.resultsPanel.ScrollBy(direction * mouseWheelRows)
+	}
+	return nil
+}
+
+// selects the row (or moves the query cursor to the text) that was clicked, a second click on the same row views it
+func (m *model) clickPanel(panelIndex int, x int, y int) tea.Cmd {
+	origin := m.panelOrigins[panelIndex]
+	x, y = x-origin.x, y-origin.y
+
+	row := -1
+	var cmd tea.Cmd
+	switch panelIndex {
+	case PanelIndexTables:
+		if row = m.tablePanel.RowAt(y); row >= 0 {
+			cmd = m.tablePanel.SelectRow(row)
+		}
+	case PanelIndexTableInfo:
+		if row = m.tableInfoPanel.RowAt(y); row >= 0 {
+			m.tableInfoPanel.SelectRow(row)
+		}
+	case PanelIndexQuery:
+		m.queryPanel.MoveCursorTo(x, y)
+	case PanelIndexResults:
+		if row = m.resultsPanel.RowAt(y); row >= 0 {
+			m.resultsPanel.SelectRow(row)
+		}
+	}
+
+	click := mouseClick{panelIndex: panelIndex, row: row, at: time.Now()}
+	doubleClick := row >= 0 && click.panelIndex == m.lastClick.panelIndex && click.row == m.lastClick.row &&
+		click.at.Sub(m.lastClick.at) < doubleClickInterval
+	m.lastClick = click
+	if doubleClick {
+		// a third click starts again
+		m.lastClick = mouseClick{}
+		return tea.Batch(cmd, m.viewSelected(panelIndex))
+	}
+	return cmd
+}
+
+// runs a command from the command palette by pressing its key, in the panel it works in
+func (m *model) runCommand(command keys.Command) tea.Cmd {
+	press := func() tea.Msg {
+		return commands.CommandKeyMsg(keyMsg(command.Binding.Keys()[0]))
+	}
+	panelIndex, ok := commandPanels[command.Panel]
+	if !ok || panelIndex == m.activePanelIndex {
+		return press
+	}
+	var cmd tea.Cmd
+	if m.layout.LeftHidden && (panelIndex == PanelIndexTables || panelIndex == PanelIndexTableInfo) {
+		cmd = tea.Batch(m.setLeftPanelHidden(false), commands.SaveLayout(m.layout))
+	}
+	return tea.Sequence(cmd, commands.SetActivePanel(panelIndex), press)
+}
+
+// views the data of the selected table, or the selected row of the table info or results
+func (m *model) viewSelected(panelIndex int) tea.Cmd {
+	switch panelIndex {
+	case PanelIndexTables:
+		return commands.GetTableRows(m.db, db.TableQuery{Table: m.tablePanel.GetSelectedTable()})
+	case PanelIndexResults:
+		if !m.showResultRowPopup && m.resultsPanel.GetSelectedRow() != nil {
+			m.showResultRecord()
+			m.showResultRowPopup = true
+		}
+	case PanelIndexTableInfo:
+		if !m.showResultRowPopup {
+			m.resultRowPopup.SetData(nil, m.tableInfoPanel.GetSelectedRow())
+			m.showResultRowPopup = true
+		}
+	}
+	return nil
+}
+
+func (m *model) setLeftPanelHidden(hidden bool) tea.Cmd {
+	m.layout.LeftHidden = hidden
+	if hidden {
+		m.selectablePanelCount = 2
+		m.adjustSizes()
+		if m.activePanelIndex < 2 {
+			return commands.SetActivePanel(PanelIndexQuery)
+		}
+		return nil
 	}
+	m.selectablePanelCount = 4
+	m.adjustSizes()
+	return nil
+}
+
+// keeps the saved (or dragged) sizes within the limits
+func (m *model) clampLayout() {
+	m.layout.LeftWidth = min(max(m.layout.LeftWidth, minLeftWidth), maxLeftWidth)
+	m.layout.ResultsHeight = min(max(m.layout.ResultsHeight, minResultsHeight), maxResultsHeight)
+}
+
+// changes the width of the tables panels and the height of the results panel by the given percentages
+func (m *model) resizeLayout(leftWidth int, resultsHeight int) tea.Cmd {
+	m.zoomed = false
+	m.layout.LeftWidth += leftWidth
+	m.layout.ResultsHeight += resultsHeight
+	m.clampLayout()
+	m.adjustSizes()
+	return commands.SaveLayout(m.layout)
+}
+
+// the divider at the screen position, where a mouse drag would resize the panels
+func (m model) dividerAt(x int, y int) divider {
+	if m.zoomed || y < TitleBarHeight || y >= m.height-StatusBarHeight {
+		return dividerNone
+	}
+	if m.leftDividerX >= 0 && x >= m.leftDividerX-1 && x <= m.leftDividerX+1 {
+		return dividerLeft
+	}
+	if m.resultsDividerY >= 0 && x > m.leftDividerX+1 && y >= m.resultsDividerY-1 && y <= m.resultsDividerY {
+		return dividerResults
+	}
+	return dividerNone
+}
+
+// moves the divider being dragged to the mouse position
+func (m *model) dragDivider(x int, y int) {
+	switch m.dragging {
+	case dividerLeft:
+		m.layout.LeftWidth = ((x-4)*100 + m.width/2) / max(m.width, 1)
+	case dividerResults:
+		available := max(m.height-TitleBarHeight-StatusBarHeight-4, 1)
+		resultsHeight := m.height - StatusBarHeight - y - 2
+		m.layout.ResultsHeight = (resultsHeight*100 + available/2) / available
+	}
+	m.clampLayout()
+	m.adjustSizes()
 }
 
 func (m model) View() string {
@@ -408,7 +1712,7 @@ func (m model) View() string {
 		m.tablePanel.View(),
 		m.tableInfoPanel.View(),
 	)
-	if m.leftPanelHidden {
+	if m.layout.LeftHidden {
 		left = ""
 	}
 
@@ -421,6 +1725,18 @@ func (m model) View() string {
 		left+lipgloss.NewStyle().MarginRight(1).Render(),
 		right,
 	)
+	if m.zoomed {
+		switch m.activePanelIndex {
+		case PanelIndexTables:
+			mainContent = m.tablePanel.View()
+		case PanelIndexTableInfo:
+			mainContent = m.tableInfoPanel.View()
+		case PanelIndexQuery:
+			mainContent = m.queryPanel.View()
+		case PanelIndexResults:
+			mainContent = m.resultsPanel.View()
+		}
+	}
 
 	contentView := mainContent
 	if len(m.errorMessage) > 0 {
@@ -435,6 +1751,54 @@ func (m model) View() string {
 		y := m.height/2 - 2 - lipgloss.Height(p)/2
 		contentView = style.PlaceOverlay(x, y, p, mainContent)
 	}
+	if m.showFormPopup {
+		p := m.formPopup.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showConfirmPopup {
+		p := m.confirmPopup.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showExplainPopup {
+		p := m.explainPopup.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showCellViewer {
+		p := m.cellViewer.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showDiffPopup {
+		p := m.diffPopup.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showChartPopup {
+		p := m.chartPopup.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showColumnStats {
+		p := m.columnStats.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+	if m.showMessageLog {
+		p := m.messageLog.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
 	if m.showHelpPopup {
 		p := m.help.View(keys.DefaultKeyMap)
 		x := m.width/2 - lipgloss.Width(p)/2
@@ -442,8 +1806,26 @@ func (m model) View() string {
 		helpStyle := style.BasePanelStyle.BorderForeground(colour.HelpBorder)
 		contentView = style.PlaceOverlay(x, y, helpStyle.Render(p), mainContent)
 	}
+	if m.showCommandPalette {
+		p := m.commandPalette.View()
+		x := m.width/2 - lipgloss.Width(p)/2
+		y := m.height/2 - 2 - lipgloss.Height(p)/2
+		contentView = style.PlaceOverlay(x, y, p, mainContent)
+	}
+
+	// toasts go in the bottom right corner, over any popup
+	if !m.toasts.IsEmpty() {
+		p := m.toasts.View()
+		x := max(lipgloss.Width(contentView)-lipgloss.Width(p)-1, 0)
+		y := max(lipgloss.Height(contentView)-lipgloss.Height(p), 0)
+		contentView = style.PlaceOverlay(x, y, p, contentView)
+	}
+
+	// the buffer's saved state changes as it's typed in, so it's read as the status bar is drawn
+	m.statusBar.SetBuffer(m.queryPanel.GetBufferName(), m.queryPanel.IsDirty(m.queryPanel.GetFilename()))
 
-	return appStyle.Render(lipgloss.JoinVertical(lipgloss.Center,
+	// the sequence takes no space on screen
+	return m.terminalCopy + appStyle.Render(lipgloss.JoinVertical(lipgloss.Center,
 		m.titleBar.View(),
 		contentView,
 		m.statusBar.View(),
@@ -1,5 +1,29 @@
 package ui
 
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
 func isInBounds(x int, y int, b bounds) bool {
 	return x > b.x1 && x < b.x2 && y > b.y1 && y < b.y2
 }
+
+// the key press for a key as it's named in a key binding, such as "f5", "ctrl+s", "alt+n" or "X"
+func keyMsg(k string) tea.KeyMsg {
+	msg := tea.KeyMsg{}
+	if strings.HasPrefix(k, "alt+") {
+		msg.Alt = true
+		k = strings.TrimPrefix(k, "alt+")
+	}
+	for t := tea.KeyF20; t <= tea.KeyBackspace; t++ {
+		if t != tea.KeyRunes && t.String() == k {
+			msg.Type = t
+			return msg
+		}
+	}
+	msg.Type = tea.KeyRunes
+	msg.Runes = []rune(k)
+	return msg
+}
@@ -27,6 +27,11 @@ type database struct {
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
+	ReadOnly bool   `mapstructure:"readOnly"`
+	// the classes of destructive statement to confirm before running, all of them when not set
+	ConfirmStatements []string `mapstructure:"confirmStatements"`
+	// statements run whenever the session's connection is opened, e.g. SET search_path
+	InitStatements []string `mapstructure:"initStatements"`
 }
 
 func main() {
@@ -53,10 +58,15 @@ func main() {
 	}
 
 	dbAlias := os.Args[1]
-	conn, ok := cfg.Databases[dbAlias]
-	if !ok {
+	if _, ok := cfg.Databases[dbAlias]; !ok {
 		exitWithError("no config found for the specified database\n(see https://github.com/wheelibin/qrypad/blob/main/README.md)\n\n", nil)
 	}
+	// any connection can be opened later (e.g. to compare results), so check them all up front
+	for alias, conn := range cfg.Databases {
+		if _, err := conn.confirmClasses(); err != nil {
+			exitWithError(fmt.Sprintf("invalid confirmStatements for the database '%s'\n(see https://github.com/wheelibin/qrypad/blob/main/README.md)\n\n", alias), err)
+		}
+	}
 
 	dir, err := commands.GetOutputDir()
 	if err != nil {
@@ -69,6 +79,34 @@ func main() {
 	}
 	defer f.Close()
 
+	connectAlias := func(alias string) (db.DBConn, error) {
+		conn, ok := cfg.Databases[alias]
+		if !ok {
+			return db.DBConn{}, fmt.Errorf("no config found for the database '%s'", alias)
+		}
+		return connect(conn)
+	}
+	dbConn, err := connectAlias(dbAlias)
+	if err != nil {
+		exitWithError("error connecting to database\n\n", err)
+	}
+	defer dbConn.Close()
+
+	m := ui.NewModel(dbAlias, dbConn, connectAlias)
+
+	p := tea.NewProgram(
+		m,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+		tea.WithReportFocus(),
+	)
+	if _, err := p.Run(); err != nil {
+		exitWithError("unexpected error\n\n", err)
+	}
+}
+
+// opens a connection pool and session for the configured database
+func connect(conn database) (db.DBConn, error) {
 	var (
 		connString string
 		driver     string
@@ -76,28 +114,55 @@ func main() {
 	switch conn.Driver {
 	case db.DriverNameMySQL:
 		connString = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", conn.User, conn.Password, conn.Host, conn.Port, conn.Database)
+		if conn.ReadOnly {
+			// the driver sets unknown params as session variables, the same as SET SESSION TRANSACTION READ ONLY
+			connString += "?transaction_read_only=1"
+		}
 		driver = conn.Driver
 	case db.DriverNamePostgres:
 		connString = fmt.Sprintf("postgres://%s:%s@%s:%d/%s", conn.User, conn.Password, conn.Host, conn.Port, conn.Database)
+		if conn.ReadOnly {
+			// sent as a run-time parameter when each session starts
+			connString += "?default_transaction_read_only=on"
+		}
 		driver = "pgx"
 	}
-	dbConn, err := sql.Open(driver, connString)
+	confirmClasses, err := conn.confirmClasses()
 	if err != nil {
-		exitWithError("error connecting to database\n\n", err)
+		return db.DBConn{}, err
+	}
+	sqlDB, err := sql.Open(driver, connString)
+	if err != nil {
+		return db.DBConn{}, err
 	}
-	defer dbConn.Close()
 
-	m := ui.NewModel(dbAlias, db.DBConn{DB: dbConn, DriverName: conn.Driver})
+	return db.DBConn{
+		DB:             sqlDB,
+		DriverName:     conn.Driver,
+		Host:           conn.Host,
+		Port:           conn.Port,
+		User:           conn.User,
+		Database:       conn.Database,
+		ReadOnly:       conn.ReadOnly,
+		ConfirmClasses: confirmClasses,
+		Session:        db.NewSession(conn.InitStatements),
+	}, nil
+}
 
-	p := tea.NewProgram(
-		m,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-		tea.WithReportFocus(),
-	)
-	if _, err := p.Run(); err != nil {
-		exitWithError("unexpected error\n\n", err)
+// the classes of statement the connection confirms before running, an error when one isn't known
+func (conn database) confirmClasses() ([]db.StatementClass, error) {
+	if conn.ConfirmStatements == nil {
+		return db.DefaultConfirmClasses, nil
+	}
+	classes := []db.StatementClass{}
+	for _, name := range conn.ConfirmStatements {
+		class, err := db.ParseStatementClass(name)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
 	}
+	return classes, nil
 }
 
 func exitWithError(msg string, err error) {